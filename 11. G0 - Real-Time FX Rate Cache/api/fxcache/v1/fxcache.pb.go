@@ -0,0 +1,621 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: fxcache/v1/fxcache.proto
+
+package fxcachev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WatchResponse_Kind int32
+
+const (
+	WatchResponse_KIND_UNSPECIFIED WatchResponse_Kind = 0
+	// The rate was set. rate.ttl_ms is its remaining lifetime.
+	WatchResponse_KIND_SET WatchResponse_Kind = 1
+	// The rate expired. rate holds the last value.
+	WatchResponse_KIND_EXPIRED WatchResponse_Kind = 2
+	// The rate was deleted or evicted. rate holds the last value.
+	WatchResponse_KIND_DELETED WatchResponse_Kind = 3
+)
+
+// Enum value maps for WatchResponse_Kind.
+var (
+	WatchResponse_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "KIND_SET",
+		2: "KIND_EXPIRED",
+		3: "KIND_DELETED",
+	}
+	WatchResponse_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"KIND_SET":         1,
+		"KIND_EXPIRED":     2,
+		"KIND_DELETED":     3,
+	}
+)
+
+func (x WatchResponse_Kind) Enum() *WatchResponse_Kind {
+	p := new(WatchResponse_Kind)
+	*p = x
+	return p
+}
+
+func (x WatchResponse_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WatchResponse_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_fxcache_v1_fxcache_proto_enumTypes[0].Descriptor()
+}
+
+func (WatchResponse_Kind) Type() protoreflect.EnumType {
+	return &file_fxcache_v1_fxcache_proto_enumTypes[0]
+}
+
+func (x WatchResponse_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WatchResponse_Kind.Descriptor instead.
+func (WatchResponse_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{8, 0}
+}
+
+// Rate is a cached rate for a currency pair such as "USD/THB".
+type Rate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Pair  string                 `protobuf:"bytes,1,opt,name=pair,proto3" json:"pair,omitempty"`
+	Rate  float64                `protobuf:"fixed64,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	// Remaining lifetime in milliseconds.
+	TtlMs         int64 `protobuf:"varint,3,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rate) Reset() {
+	*x = Rate{}
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rate) ProtoMessage() {}
+
+func (x *Rate) ProtoReflect() protoreflect.Message {
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rate.ProtoReflect.Descriptor instead.
+func (*Rate) Descriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Rate) GetPair() string {
+	if x != nil {
+		return x.Pair
+	}
+	return ""
+}
+
+func (x *Rate) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+func (x *Rate) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+type GetRateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pair          string                 `protobuf:"bytes,1,opt,name=pair,proto3" json:"pair,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRateRequest) Reset() {
+	*x = GetRateRequest{}
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRateRequest) ProtoMessage() {}
+
+func (x *GetRateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRateRequest.ProtoReflect.Descriptor instead.
+func (*GetRateRequest) Descriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetRateRequest) GetPair() string {
+	if x != nil {
+		return x.Pair
+	}
+	return ""
+}
+
+type GetRateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rate          *Rate                  `protobuf:"bytes,1,opt,name=rate,proto3" json:"rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRateResponse) Reset() {
+	*x = GetRateResponse{}
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRateResponse) ProtoMessage() {}
+
+func (x *GetRateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRateResponse.ProtoReflect.Descriptor instead.
+func (*GetRateResponse) Descriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetRateResponse) GetRate() *Rate {
+	if x != nil {
+		return x.Rate
+	}
+	return nil
+}
+
+type SetRateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Pair  string                 `protobuf:"bytes,1,opt,name=pair,proto3" json:"pair,omitempty"`
+	Rate  float64                `protobuf:"fixed64,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	// Lifetime in milliseconds. Zero means the cache's default TTL.
+	TtlMs         int64 `protobuf:"varint,3,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetRateRequest) Reset() {
+	*x = SetRateRequest{}
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRateRequest) ProtoMessage() {}
+
+func (x *SetRateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRateRequest.ProtoReflect.Descriptor instead.
+func (*SetRateRequest) Descriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SetRateRequest) GetPair() string {
+	if x != nil {
+		return x.Pair
+	}
+	return ""
+}
+
+func (x *SetRateRequest) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+func (x *SetRateRequest) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+type SetRateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rate          *Rate                  `protobuf:"bytes,1,opt,name=rate,proto3" json:"rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetRateResponse) Reset() {
+	*x = SetRateResponse{}
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetRateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetRateResponse) ProtoMessage() {}
+
+func (x *SetRateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetRateResponse.ProtoReflect.Descriptor instead.
+func (*SetRateResponse) Descriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SetRateResponse) GetRate() *Rate {
+	if x != nil {
+		return x.Rate
+	}
+	return nil
+}
+
+type DeleteRateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pair          string                 `protobuf:"bytes,1,opt,name=pair,proto3" json:"pair,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRateRequest) Reset() {
+	*x = DeleteRateRequest{}
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRateRequest) ProtoMessage() {}
+
+func (x *DeleteRateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRateRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRateRequest) Descriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteRateRequest) GetPair() string {
+	if x != nil {
+		return x.Pair
+	}
+	return ""
+}
+
+type DeleteRateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRateResponse) Reset() {
+	*x = DeleteRateResponse{}
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRateResponse) ProtoMessage() {}
+
+func (x *DeleteRateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRateResponse.ProtoReflect.Descriptor instead.
+func (*DeleteRateResponse) Descriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{6}
+}
+
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The pairs to watch. At least one is required.
+	Pairs         []string `protobuf:"bytes,1,rep,name=pairs,proto3" json:"pairs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WatchRequest) GetPairs() []string {
+	if x != nil {
+		return x.Pairs
+	}
+	return nil
+}
+
+// WatchResponse is one change to a watched pair.
+type WatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          WatchResponse_Kind     `protobuf:"varint,1,opt,name=kind,proto3,enum=fxcache.v1.WatchResponse_Kind" json:"kind,omitempty"`
+	Rate          *Rate                  `protobuf:"bytes,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchResponse) Reset() {
+	*x = WatchResponse{}
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchResponse) ProtoMessage() {}
+
+func (x *WatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_fxcache_v1_fxcache_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchResponse.ProtoReflect.Descriptor instead.
+func (*WatchResponse) Descriptor() ([]byte, []int) {
+	return file_fxcache_v1_fxcache_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchResponse) GetKind() WatchResponse_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return WatchResponse_KIND_UNSPECIFIED
+}
+
+func (x *WatchResponse) GetRate() *Rate {
+	if x != nil {
+		return x.Rate
+	}
+	return nil
+}
+
+var File_fxcache_v1_fxcache_proto protoreflect.FileDescriptor
+
+const file_fxcache_v1_fxcache_proto_rawDesc = "" +
+	"\n" +
+	"\x18fxcache/v1/fxcache.proto\x12\n" +
+	"fxcache.v1\"E\n" +
+	"\x04Rate\x12\x12\n" +
+	"\x04pair\x18\x01 \x01(\tR\x04pair\x12\x12\n" +
+	"\x04rate\x18\x02 \x01(\x01R\x04rate\x12\x15\n" +
+	"\x06ttl_ms\x18\x03 \x01(\x03R\x05ttlMs\"$\n" +
+	"\x0eGetRateRequest\x12\x12\n" +
+	"\x04pair\x18\x01 \x01(\tR\x04pair\"7\n" +
+	"\x0fGetRateResponse\x12$\n" +
+	"\x04rate\x18\x01 \x01(\v2\x10.fxcache.v1.RateR\x04rate\"O\n" +
+	"\x0eSetRateRequest\x12\x12\n" +
+	"\x04pair\x18\x01 \x01(\tR\x04pair\x12\x12\n" +
+	"\x04rate\x18\x02 \x01(\x01R\x04rate\x12\x15\n" +
+	"\x06ttl_ms\x18\x03 \x01(\x03R\x05ttlMs\"7\n" +
+	"\x0fSetRateResponse\x12$\n" +
+	"\x04rate\x18\x01 \x01(\v2\x10.fxcache.v1.RateR\x04rate\"'\n" +
+	"\x11DeleteRateRequest\x12\x12\n" +
+	"\x04pair\x18\x01 \x01(\tR\x04pair\"\x14\n" +
+	"\x12DeleteRateResponse\"$\n" +
+	"\fWatchRequest\x12\x14\n" +
+	"\x05pairs\x18\x01 \x03(\tR\x05pairs\"\xb9\x01\n" +
+	"\rWatchResponse\x122\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x1e.fxcache.v1.WatchResponse.KindR\x04kind\x12$\n" +
+	"\x04rate\x18\x02 \x01(\v2\x10.fxcache.v1.RateR\x04rate\"N\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\f\n" +
+	"\bKIND_SET\x10\x01\x12\x10\n" +
+	"\fKIND_EXPIRED\x10\x02\x12\x10\n" +
+	"\fKIND_DELETED\x10\x032\xa2\x02\n" +
+	"\vRateService\x12B\n" +
+	"\aGetRate\x12\x1a.fxcache.v1.GetRateRequest\x1a\x1b.fxcache.v1.GetRateResponse\x12B\n" +
+	"\aSetRate\x12\x1a.fxcache.v1.SetRateRequest\x1a\x1b.fxcache.v1.SetRateResponse\x12K\n" +
+	"\n" +
+	"DeleteRate\x12\x1d.fxcache.v1.DeleteRateRequest\x1a\x1e.fxcache.v1.DeleteRateResponse\x12>\n" +
+	"\x05Watch\x12\x18.fxcache.v1.WatchRequest\x1a\x19.fxcache.v1.WatchResponse0\x01B5Z3g0-real-time-fx-rate-cache/api/fxcache/v1;fxcachev1b\x06proto3"
+
+var (
+	file_fxcache_v1_fxcache_proto_rawDescOnce sync.Once
+	file_fxcache_v1_fxcache_proto_rawDescData []byte
+)
+
+func file_fxcache_v1_fxcache_proto_rawDescGZIP() []byte {
+	file_fxcache_v1_fxcache_proto_rawDescOnce.Do(func() {
+		file_fxcache_v1_fxcache_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_fxcache_v1_fxcache_proto_rawDesc), len(file_fxcache_v1_fxcache_proto_rawDesc)))
+	})
+	return file_fxcache_v1_fxcache_proto_rawDescData
+}
+
+var file_fxcache_v1_fxcache_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_fxcache_v1_fxcache_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_fxcache_v1_fxcache_proto_goTypes = []any{
+	(WatchResponse_Kind)(0),    // 0: fxcache.v1.WatchResponse.Kind
+	(*Rate)(nil),               // 1: fxcache.v1.Rate
+	(*GetRateRequest)(nil),     // 2: fxcache.v1.GetRateRequest
+	(*GetRateResponse)(nil),    // 3: fxcache.v1.GetRateResponse
+	(*SetRateRequest)(nil),     // 4: fxcache.v1.SetRateRequest
+	(*SetRateResponse)(nil),    // 5: fxcache.v1.SetRateResponse
+	(*DeleteRateRequest)(nil),  // 6: fxcache.v1.DeleteRateRequest
+	(*DeleteRateResponse)(nil), // 7: fxcache.v1.DeleteRateResponse
+	(*WatchRequest)(nil),       // 8: fxcache.v1.WatchRequest
+	(*WatchResponse)(nil),      // 9: fxcache.v1.WatchResponse
+}
+var file_fxcache_v1_fxcache_proto_depIdxs = []int32{
+	1, // 0: fxcache.v1.GetRateResponse.rate:type_name -> fxcache.v1.Rate
+	1, // 1: fxcache.v1.SetRateResponse.rate:type_name -> fxcache.v1.Rate
+	0, // 2: fxcache.v1.WatchResponse.kind:type_name -> fxcache.v1.WatchResponse.Kind
+	1, // 3: fxcache.v1.WatchResponse.rate:type_name -> fxcache.v1.Rate
+	2, // 4: fxcache.v1.RateService.GetRate:input_type -> fxcache.v1.GetRateRequest
+	4, // 5: fxcache.v1.RateService.SetRate:input_type -> fxcache.v1.SetRateRequest
+	6, // 6: fxcache.v1.RateService.DeleteRate:input_type -> fxcache.v1.DeleteRateRequest
+	8, // 7: fxcache.v1.RateService.Watch:input_type -> fxcache.v1.WatchRequest
+	3, // 8: fxcache.v1.RateService.GetRate:output_type -> fxcache.v1.GetRateResponse
+	5, // 9: fxcache.v1.RateService.SetRate:output_type -> fxcache.v1.SetRateResponse
+	7, // 10: fxcache.v1.RateService.DeleteRate:output_type -> fxcache.v1.DeleteRateResponse
+	9, // 11: fxcache.v1.RateService.Watch:output_type -> fxcache.v1.WatchResponse
+	8, // [8:12] is the sub-list for method output_type
+	4, // [4:8] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_fxcache_v1_fxcache_proto_init() }
+func file_fxcache_v1_fxcache_proto_init() {
+	if File_fxcache_v1_fxcache_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_fxcache_v1_fxcache_proto_rawDesc), len(file_fxcache_v1_fxcache_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_fxcache_v1_fxcache_proto_goTypes,
+		DependencyIndexes: file_fxcache_v1_fxcache_proto_depIdxs,
+		EnumInfos:         file_fxcache_v1_fxcache_proto_enumTypes,
+		MessageInfos:      file_fxcache_v1_fxcache_proto_msgTypes,
+	}.Build()
+	File_fxcache_v1_fxcache_proto = out.File
+	file_fxcache_v1_fxcache_proto_goTypes = nil
+	file_fxcache_v1_fxcache_proto_depIdxs = nil
+}
@@ -1,144 +1,759 @@
-package main
+// Package fxcache implements a generic, thread-safe in-memory cache with a
+// Time-To-Live per entry, built for real-time FX rates and quotes.
+package fxcache
 
 import (
+	"container/heap"
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // CacheEntry represents an entry in the TTL cache.
-type CacheEntry struct {
-	Value float64
+type CacheEntry[V any] struct {
+	Value V
 	// ExpiresAt is the Unix timestamp in milliseconds when the entry will expire.
 	ExpiresAt int64
 }
 
+// cacheItem is the stored form of a CacheEntry, with the bookkeeping the
+// cache needs for expiry, capacity limits and background refresh.
+type cacheItem[K comparable, V any] struct {
+	CacheEntry[V]
+	key K
+	// wakeAt is the Unix timestamp in milliseconds when the janitor next has
+	// to look at the entry, and index its position in the expiry heap.
+	wakeAt int64
+	index  int
+	// size is the entry's cost towards Config.MaxBytes.
+	size int64
+	// ttl is the lifetime the entry was stored with, reused when it is refreshed.
+	ttl time.Duration
+	// staleAt is the Unix timestamp in milliseconds after which a read
+	// triggers a background refresh (the soft TTL).
+	staleAt int64
+	// read records whether the entry was read since it was stored, which
+	// makes it a candidate for refresh-ahead.
+	read atomic.Bool
+}
+
 // TTLCache is a thread-safe in-memory cache with a Time-To-Live (TTL) for each entry.
-type TTLCache struct {
-	cache       map[string]CacheEntry
+// It is generic over the key type K and the value type V, so it can hold a
+// single float64 rate as well as a full quote struct.
+type TTLCache[K comparable, V any] struct {
+	cache       map[K]*cacheItem[K, V]
 	defaultTTL  time.Duration
 	mu          sync.RWMutex
 	stopJanitor chan struct{}
+	stopWorkers chan struct{}
+	clock       Clock
+
+	// The janitor sleeps on a timer until the earliest deadline in the
+	// expiry heap. All of these are guarded by mu.
+	expiry          expiryHeap[K, V]
+	janitor         Timer
+	janitorAt       int64
+	janitorInterval time.Duration
+	janitorStopped  bool
+	lastSweep       int64
+
+	// Capacity limits. The evictor is nil when the cache is unbounded.
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	sizer      func(key, value any) int64
+	evictor    evictor[K]
+	// evictMu guards evictor, which Get updates while holding only a read lock.
+	evictMu   sync.Mutex
+	evictions atomic.Uint64
+
+	// Counters reported by Stats.
+	hits, misses, sets, expirations atomic.Uint64
+	lastSweepNanos                  atomic.Int64
+
+	// Read-through loading. loads holds the in-flight loader call per key and
+	// is guarded by loadMu; failures holds cached loader errors and is
+	// guarded by mu.
+	loadMu      sync.Mutex
+	loads       map[K]*loadCall[V]
+	failures    map[K]failedLoad
+	negativeTTL time.Duration
+	// loader is the registered loader used for background refreshes. It is
+	// guarded by loadMu.
+	loader       LoaderFunc[K, V]
+	softTTL      time.Duration
+	refreshAhead time.Duration
+	refreshes    sync.WaitGroup
+	// background is the context of background refreshes, canceled by Close.
+	background       context.Context
+	cancelBackground context.CancelFunc
+
+	// workers tracks the janitor and the other background goroutines, such
+	// as the periodic snapshot writer.
+	workers sync.WaitGroup
+	onError func(error)
+
+	// closed is set by Close while holding both mu and loadMu, so a method
+	// holding either one sees it. closeDone is closed once every background
+	// goroutine has exited.
+	closed    atomic.Bool
+	closeOnce sync.Once
+	closeDone chan struct{}
+
+	// wal is the write-ahead log every Set and Delete is appended to, or nil
+	// if logging is disabled. Appends happen under mu.
+	wal     *wal
+	walPath string
+
+	// Eviction and set callbacks and the notifications queued for them, all
+	// guarded by mu.
+	onEvicted []EvictionFunc[K, V]
+	onSet     []SetFunc[K, V]
+	pending   []notification[K, V]
+	// failed holds errors met under mu, such as failed log appends, for
+	// onError once mu is released.
+	failed []error
+
+	// subscribers is guarded by mu and copied on write. publishMu keeps the
+	// events of concurrent writers in the order they changed the cache.
+	subscribers   []*subscriber[K, V]
+	publishMu     sync.Mutex
+	droppedEvents atomic.Uint64
 }
 
+// ErrClosed is returned by the methods of a closed cache that return an
+// error. Methods without an error result ignore writes after Close, and those
+// that report whether they wrote return false.
+var ErrClosed = errors.New("cache is closed")
+
+// Configuration errors returned by New, NewTTLCache and NewTTLCacheWithConfig,
+// wrapped with the offending values. Match them with errors.Is.
+var (
+	// ErrNegativeTTL means the default TTL, the soft TTL or the negative-
+	// caching TTL is negative.
+	ErrNegativeTTL = errors.New("TTL must not be negative")
+	// ErrJanitorTooSlow means the janitor interval is longer than the
+	// default TTL, so entries would linger long after they expired.
+	ErrJanitorTooSlow = errors.New("janitor interval must not be greater than default TTL")
+	// ErrNegativeInterval means the janitor interval, the refresh-ahead
+	// window, the snapshot interval or the WAL sync interval is negative.
+	ErrNegativeInterval = errors.New("interval must not be negative")
+	// ErrNegativeLimit means MaxEntries or MaxBytes is negative.
+	ErrNegativeLimit = errors.New("capacity limit must not be negative")
+	// ErrUnknownEvictionPolicy means EvictionPolicy is not one of the
+	// Evict constants.
+	ErrUnknownEvictionPolicy = errors.New("unknown eviction policy")
+	// ErrNoSnapshotPath means SnapshotInterval is set without SnapshotPath.
+	ErrNoSnapshotPath = errors.New("snapshot interval requires a snapshot path")
+	// ErrUnknownWALSync means WALSync is not one of the WALSync constants.
+	ErrUnknownWALSync = errors.New("unknown WAL sync policy")
+)
+
 const (
 	DefaultCacheTTL        = 5 * time.Second
 	DefaultJanitorInterval = 50 * time.Millisecond
 )
 
+// FXRateCache is a TTLCache keyed by currency pair (e.g. "USD/THB") that stores
+// a single float64 rate per pair.
+type FXRateCache = TTLCache[string, float64]
+
+// NewFXRateCache creates a new FXRateCache. See NewTTLCache for the meaning of
+// the arguments.
+func NewFXRateCache(defaultTTL time.Duration, janitorInterval ...time.Duration) (*FXRateCache, error) {
+	return NewTTLCache[string, float64](defaultTTL, janitorInterval...)
+}
+
+// RateWriter takes the rate writes of a server or poller. An FXRateCache is
+// one; a cluster.Node is another that also replicates the writes.
+type RateWriter interface {
+	Set(pair string, rate float64, ttl ...time.Duration)
+	SetMany(rates map[string]float64, ttl ...time.Duration)
+	// Delete removes pair and reports whether it held a live rate.
+	Delete(pair string) bool
+}
+
+// Config holds the settings used by NewTTLCacheWithConfig. Zero values fall
+// back to the package defaults.
+type Config struct {
+	// DefaultTTL is the lifetime of entries set without an explicit TTL.
+	DefaultTTL time.Duration
+	// JanitorInterval is the minimum time between two janitor sweeps. The
+	// janitor sleeps until the next entry is due, but never wakes more often
+	// than this, so that expirations close together are handled in one sweep.
+	JanitorInterval time.Duration
+	// Clock is the time source. It defaults to SystemClock.
+	Clock Clock
+
+	// MaxEntries caps the number of entries. Zero means unbounded.
+	MaxEntries int
+	// MaxBytes caps the estimated memory used by entries, as measured by
+	// Sizer. Zero means unbounded.
+	MaxBytes int64
+	// Sizer returns the cost of one entry towards MaxBytes. It defaults to
+	// EstimateSize.
+	Sizer func(key, value any) int64
+	// EvictionPolicy chooses the entry to remove when a limit is reached.
+	EvictionPolicy EvictionPolicy
+
+	// NegativeTTL is how long a GetOrLoad loader error is cached. Zero means
+	// errors are not cached and every miss calls the loader.
+	NegativeTTL time.Duration
+
+	// SoftTTL is the age after which an entry is stale. A stale entry is
+	// still returned by Get until its hard TTL (the TTL given to Set), but
+	// the read triggers a background refresh through the loader registered
+	// with SetLoader. Zero disables stale-while-revalidate.
+	SoftTTL time.Duration
+	// RefreshAhead makes the janitor reload entries that are read between
+	// being stored and RefreshAhead before their expiry, so hot keys are
+	// refreshed before readers ever miss. Zero disables refresh-ahead.
+	RefreshAhead time.Duration
+
+	// SnapshotPath is a file the cache is loaded from when it is created,
+	// for a warm restart. A missing file is ignored.
+	SnapshotPath string
+	// SnapshotInterval makes the cache write a snapshot to SnapshotPath at
+	// this interval, and once more when the janitor is stopped. Zero
+	// disables periodic snapshots.
+	SnapshotInterval time.Duration
+	// WALPath is an append-only log that every Set, Delete and capacity
+	// eviction is written to. When the cache is created, the log is replayed
+	// on top of SnapshotPath to recover from a crash. CompactWAL folds the
+	// log into WALPath + ".snapshot". Empty disables the log.
+	WALPath string
+	// WALSync is when appends to the log are fsynced. It defaults to
+	// WALSyncAlways.
+	WALSync WALSyncPolicy
+	// WALSyncInterval is the fsync interval for WALSyncInterval. It defaults
+	// to DefaultWALSyncInterval.
+	WALSyncInterval time.Duration
+
+	// OnError receives errors from background work such as periodic
+	// snapshots and write-ahead log appends. It defaults to logging them
+	// with the log package. It is never called with the cache locked, so it
+	// may use the cache.
+	OnError func(error)
+}
+
 // NewTTLCache creates a new instance of TTLCache.
 // It takes a defaultTTL for cache entries and an optional janitorInterval
 // for the cleanup goroutine. If zero values are provided, it uses
 // DefaultCacheTTL and DefaultJanitorInterval respectively.
 // It returns an error if the provided durations are invalid (e.g., negative,
-// or a janitor interval greater than the default TTL).
-func NewTTLCache(defaultTTL time.Duration, janitorInterval ...time.Duration) (*TTLCache, error) {
-	effectiveJanitorInterval := DefaultJanitorInterval
+// or a janitor interval greater than the default TTL). New offers the same
+// with options.
+func NewTTLCache[K comparable, V any](defaultTTL time.Duration, janitorInterval ...time.Duration) (*TTLCache[K, V], error) {
+	cfg := Config{DefaultTTL: defaultTTL}
 	if len(janitorInterval) > 0 {
-		effectiveJanitorInterval = janitorInterval[0]
+		cfg.JanitorInterval = janitorInterval[0]
 	}
+	return NewTTLCacheWithConfig[K, V](cfg)
+}
 
+// NewTTLCacheWithConfig creates a new instance of TTLCache from a Config. It
+// validates the durations the same way as NewTTLCache, and returns the
+// configuration errors listed under New for the other invalid settings.
+func NewTTLCacheWithConfig[K comparable, V any](cfg Config) (*TTLCache[K, V], error) {
 	// A negative TTL is invalid.
-	if defaultTTL < 0 {
-		return nil, fmt.Errorf("default TTL must not be negative")
+	if cfg.DefaultTTL < 0 {
+		return nil, fmt.Errorf("default %w, got %v", ErrNegativeTTL, cfg.DefaultTTL)
 	}
 	// A negative janitor interval is invalid.
-	if effectiveJanitorInterval < 0 {
-		return nil, fmt.Errorf("janitor interval must not be negative")
+	if cfg.JanitorInterval < 0 {
+		return nil, fmt.Errorf("%w: janitor interval %v", ErrNegativeInterval, cfg.JanitorInterval)
 	}
 
 	// Use defaults for zero values, allowing for easy configuration.
-	if defaultTTL == 0 {
-		defaultTTL = DefaultCacheTTL
+	if cfg.DefaultTTL == 0 {
+		cfg.DefaultTTL = DefaultCacheTTL
+	}
+
+	if cfg.JanitorInterval == 0 {
+		cfg.JanitorInterval = DefaultJanitorInterval
 	}
 
-	if effectiveJanitorInterval == 0 {
-		effectiveJanitorInterval = DefaultJanitorInterval
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock{}
+	}
+
+	// Capacity limits are optional, but cannot be negative.
+	if cfg.MaxEntries < 0 {
+		return nil, fmt.Errorf("%w: max entries %d", ErrNegativeLimit, cfg.MaxEntries)
+	}
+	if cfg.MaxBytes < 0 {
+		return nil, fmt.Errorf("%w: max bytes %d", ErrNegativeLimit, cfg.MaxBytes)
+	}
+	if cfg.Sizer == nil {
+		cfg.Sizer = EstimateSize
+	}
+	if cfg.NegativeTTL < 0 {
+		return nil, fmt.Errorf("negative-caching %w, got %v", ErrNegativeTTL, cfg.NegativeTTL)
+	}
+	if cfg.SoftTTL < 0 {
+		return nil, fmt.Errorf("soft %w, got %v", ErrNegativeTTL, cfg.SoftTTL)
+	}
+	if cfg.RefreshAhead < 0 {
+		return nil, fmt.Errorf("%w: refresh-ahead window %v", ErrNegativeInterval, cfg.RefreshAhead)
+	}
+	if cfg.SnapshotInterval < 0 {
+		return nil, fmt.Errorf("%w: snapshot interval %v", ErrNegativeInterval, cfg.SnapshotInterval)
+	}
+	if cfg.SnapshotInterval > 0 && cfg.SnapshotPath == "" {
+		return nil, fmt.Errorf("%w: snapshot interval %v", ErrNoSnapshotPath, cfg.SnapshotInterval)
+	}
+	if cfg.WALSync < WALSyncAlways || cfg.WALSync > WALSyncNever {
+		return nil, fmt.Errorf("%w %d", ErrUnknownWALSync, cfg.WALSync)
+	}
+	if cfg.WALSyncInterval < 0 {
+		return nil, fmt.Errorf("%w: WAL sync interval %v", ErrNegativeInterval, cfg.WALSyncInterval)
+	}
+	if cfg.WALSyncInterval == 0 {
+		cfg.WALSyncInterval = DefaultWALSyncInterval
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = logError
 	}
 
 	// It is inefficient for the cleanup interval to be longer than the item lifetime.
-	if effectiveJanitorInterval > defaultTTL {
-		return nil, fmt.Errorf("janitor interval (%v) must not be greater than default TTL (%v)", janitorInterval, defaultTTL)
+	if cfg.JanitorInterval > cfg.DefaultTTL {
+		return nil, fmt.Errorf("%w: janitor interval %v, default TTL %v", ErrJanitorTooSlow, cfg.JanitorInterval, cfg.DefaultTTL)
+	}
+
+	cache := &TTLCache[K, V]{
+		cache:        make(map[K]*cacheItem[K, V]),
+		defaultTTL:   cfg.DefaultTTL,
+		stopJanitor:  make(chan struct{}),
+		stopWorkers:  make(chan struct{}),
+		clock:        cfg.Clock,
+		maxEntries:   cfg.MaxEntries,
+		maxBytes:     cfg.MaxBytes,
+		sizer:        cfg.Sizer,
+		loads:        make(map[K]*loadCall[V]),
+		failures:     make(map[K]failedLoad),
+		negativeTTL:  cfg.NegativeTTL,
+		softTTL:      cfg.SoftTTL,
+		refreshAhead: cfg.RefreshAhead,
+		onError:      cfg.OnError,
+		closeDone:    make(chan struct{}),
 	}
+	cache.background, cache.cancelBackground = context.WithCancel(context.Background())
 
-	cache := &TTLCache{
-		cache:       make(map[string]CacheEntry),
-		defaultTTL:  defaultTTL,
-		stopJanitor: make(chan struct{}),
+	if cfg.MaxEntries > 0 || cfg.MaxBytes > 0 {
+		ev, err := newEvictor[K](cfg.EvictionPolicy)
+		if err != nil {
+			return nil, err
+		}
+		cache.evictor = ev
 	}
 
 	// Start the background cleanup goroutine (the "Janitor")
-	cache.startJanitor(effectiveJanitorInterval)
+	cache.startJanitor(cfg.JanitorInterval)
+
+	// Warm restart from the last snapshot, then keep snapshotting.
+	if cfg.SnapshotPath != "" {
+		if err := cache.LoadSnapshotFile(cfg.SnapshotPath); err != nil {
+			cache.Close(context.Background())
+			return nil, fmt.Errorf("load snapshot %s: %w", cfg.SnapshotPath, err)
+		}
+	}
+	// Replay the write-ahead log on top of the snapshot.
+	if cfg.WALPath != "" {
+		if err := cache.openWAL(cfg.WALPath, cfg.WALSync, cfg.WALSyncInterval); err != nil {
+			cache.Close(context.Background())
+			return nil, fmt.Errorf("open write-ahead log %s: %w", cfg.WALPath, err)
+		}
+	}
+	if cfg.SnapshotInterval > 0 {
+		cache.startSnapshots(cfg.SnapshotPath, cfg.SnapshotInterval)
+	}
 
 	return cache, nil
 }
 
-// startJanitor starts a background goroutine to clean up expired entries periodically.
-func (c *TTLCache) startJanitor(interval time.Duration) {
-	ticker := time.NewTicker(interval)
+// startJanitor starts a background goroutine that removes expired entries.
+// It sleeps until the earliest deadline instead of waking on a fixed ticker.
+func (c *TTLCache[K, V]) startJanitor(interval time.Duration) {
+	now := c.clock.Now().UnixMilli()
+	c.janitorInterval = interval
+	c.lastSweep = now
+	c.janitorAt = now + c.defaultTTL.Milliseconds()
+	c.janitor = c.clock.NewTimer(c.defaultTTL)
+
+	c.workers.Add(1)
 	go func() {
+		defer c.workers.Done()
 		for {
 			select {
-			case <-ticker.C:
+			case <-c.janitor.C():
 				c.cleanupExpired()
 			case <-c.stopJanitor:
-				ticker.Stop()
 				return
 			}
 		}
 	}()
 }
 
-// StopJanitor stops the background janitor goroutine, allowing for a graceful shutdown.
-func (c *TTLCache) StopJanitor() {
-	if c.stopJanitor != nil {
-		close(c.stopJanitor)
+// StopJanitor stops the background janitor goroutine without waiting for it.
+// Expired entries are still hidden from reads, but stay in memory, without
+// OnEvicted callbacks, until they are overwritten or deleted. The cache stays
+// usable and its other background workers keep running; call Close to shut
+// it down. It may be
+// called from callbacks and loaders, and is safe to call more than once.
+func (c *TTLCache[K, V]) StopJanitor() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopJanitorLocked()
+}
+
+// stopJanitorLocked stops the janitor once. It must be called with c.mu held.
+func (c *TTLCache[K, V]) stopJanitorLocked() {
+	if c.janitorStopped {
+		return
+	}
+	c.janitorStopped = true
+	if c.janitor != nil {
+		c.janitor.Stop()
+	}
+	close(c.stopJanitor)
+}
+
+// Close shuts the cache down: it stops the janitor, cancels the context of
+// background refreshes, ends every subscription and waits until the
+// janitor, the refreshes and the other background workers (periodic
+// snapshots, which write a final snapshot, and the write-ahead log syncer)
+// have exited, then closes the write-ahead log.
+//
+// After Close, reads still see the entries held when the cache was closed,
+// but they no longer start refreshes; writes are ignored, and methods that
+// return an error return ErrClosed.
+//
+// If ctx is done first, Close returns ctx.Err() and the shutdown finishes in
+// the background. It is safe to call more than once, and concurrently; every
+// call waits for the same shutdown. Close must not be called from an
+// OnEvicted or OnSet callback or from a loader, which may run on the
+// goroutines it waits for; call it on a new goroutine there instead.
+func (c *TTLCache[K, V]) Close(ctx context.Context) error {
+	c.beginClose()
+	select {
+	case <-c.closeDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-// cleanupExpired removes all expired entries from the cache.
-func (c *TTLCache) cleanupExpired() {
+// beginClose marks the cache closed, signals the background goroutines to
+// stop and finishes the shutdown in the background. Only the first call does
+// anything.
+func (c *TTLCache[K, V]) beginClose() {
+	c.closeOnce.Do(func() {
+		c.loadMu.Lock()
+		c.mu.Lock()
+		c.closed.Store(true)
+		c.stopJanitorLocked()
+		subs := c.subscribers
+		c.subscribers = nil
+		c.mu.Unlock()
+		c.loadMu.Unlock()
+
+		close(c.stopWorkers)
+		c.cancelBackground()
+		for _, sub := range subs {
+			sub.close()
+		}
+		go c.finishClose()
+	})
+}
+
+// finishClose waits for the background goroutines and closes the
+// write-ahead log.
+func (c *TTLCache[K, V]) finishClose() {
+	defer close(c.closeDone)
+	c.workers.Wait()
+	c.refreshes.Wait()
+
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	w := c.wal
+	c.wal = nil
+	c.mu.Unlock()
+	if w != nil {
+		if err := w.close(); err != nil {
+			c.onError(fmt.Errorf("close write-ahead log %s: %w", c.walPath, err))
+		}
+	}
+}
 
-	now := time.Now().UnixMilli()
-	for key, entry := range c.cache {
-		if now >= entry.ExpiresAt {
-			delete(c.cache, key)
+// cleanupExpired removes the entries that are due according to the expiry
+// heap, starts a refresh for hot entries that are about to expire, and
+// re-arms the janitor for the next deadline. The janitor is re-armed last, so
+// once it sleeps again the callbacks and refreshes of this sweep have run.
+func (c *TTLCache[K, V]) cleanupExpired() {
+	start := time.Now()
+	c.mu.Lock()
+	now := c.clock.Now().UnixMilli()
+	refresh := c.sweepLocked(now)
+	for key, failure := range c.failures {
+		if now >= failure.expiresAt {
+			delete(c.failures, key)
 		}
 	}
+	c.lastSweep = now
+	c.lastSweepNanos.Store(int64(time.Since(start)))
+	c.unlockAndNotify()
+
+	for _, key := range refresh {
+		c.refresh(key)
+	}
+
+	c.mu.Lock()
+	c.armJanitorLocked(c.clock.Now().UnixMilli())
+	c.mu.Unlock()
 }
 
 // Set adds or updates a key-value pair in the cache. It takes an optional
 // ttl (time.Duration) for the entry. If no TTL is provided, it uses the
 // cache's default TTL.
-func (c *TTLCache) Set(key string, value float64, ttl ...time.Duration) {
+func (c *TTLCache[K, V]) Set(key K, value V, ttl ...time.Duration) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return
+	}
+
+	now := c.clock.Now().UnixMilli()
+	c.storeLocked(key, value, now, now+c.effectiveTTL(ttl).Milliseconds())
+}
 
-	effectiveTTL := c.defaultTTL
+// effectiveTTL returns the optional TTL passed to a setter, or the cache's
+// default TTL if none (or a non-positive one) was given.
+func (c *TTLCache[K, V]) effectiveTTL(ttl []time.Duration) time.Duration {
 	if len(ttl) > 0 && ttl[0] > 0 {
-		effectiveTTL = ttl[0]
+		return ttl[0]
+	}
+	return c.defaultTTL
+}
+
+// storeLocked inserts or overwrites an entry that expires at expiresAt,
+// evicting others first if a capacity limit would be exceeded. now is the
+// current time in Unix milliseconds. It must be called with c.mu held.
+func (c *TTLCache[K, V]) storeLocked(key K, value V, now, expiresAt int64) {
+	var size int64
+	if c.maxBytes > 0 {
+		size = c.sizer(key, value)
+	}
+
+	// A fresh value supersedes any cached loader error.
+	delete(c.failures, key)
+
+	item, exists := c.cache[key]
+	if c.evictor != nil {
+		c.makeRoomLocked(key, exists, size)
+	}
+
+	replaced := false
+	if exists {
+		reason := ReasonReplaced
+		if now >= item.ExpiresAt {
+			reason = ReasonExpired
+			c.expirations.Add(1)
+		}
+		replaced = reason == ReasonReplaced
+		c.recordEvictionLocked(key, item.Value, reason)
+		c.bytes -= item.size
+		item.Value = value
+		item.size = size
+	} else {
+		item = &cacheItem[K, V]{
+			CacheEntry: CacheEntry[V]{Value: value},
+			key:        key,
+			index:      -1,
+			size:       size,
+		}
+		c.cache[key] = item
+	}
+	c.bytes += size
+	c.setExpiryLocked(item, now, expiresAt)
+	c.sets.Add(1)
+	c.recordSetLocked(key, value, expiresAt, replaced)
+
+	if c.evictor != nil {
+		c.evictMu.Lock()
+		if exists {
+			c.evictor.touch(key)
+		} else {
+			c.evictor.add(key)
+		}
+		c.evictMu.Unlock()
+	}
+}
+
+// setExpiryLocked sets an entry's hard and soft deadlines, marks it as not
+// read yet and logs it to the write-ahead log. It must be called with c.mu held.
+func (c *TTLCache[K, V]) setExpiryLocked(item *cacheItem[K, V], now, expiresAt int64) {
+	item.ExpiresAt = expiresAt
+	item.ttl = time.Duration(expiresAt-now) * time.Millisecond
+	item.staleAt = expiresAt
+	if c.softTTL > 0 {
+		item.staleAt = min(now+c.softTTL.Milliseconds(), expiresAt)
+	}
+	item.read.Store(false)
+	c.scheduleLocked(item, now)
+	c.logSetLocked(item)
+}
+
+// makeRoomLocked evicts entries until key can be stored with the given size
+// without exceeding MaxEntries or MaxBytes. An entry that is larger than
+// MaxBytes on its own is still stored once every other entry is gone.
+func (c *TTLCache[K, V]) makeRoomLocked(key K, exists bool, size int64) {
+	for {
+		bytes := c.bytes + size
+		if exists {
+			bytes -= c.cache[key].size
+		}
+		overEntries := !exists && c.maxEntries > 0 && len(c.cache) >= c.maxEntries
+		overBytes := c.maxBytes > 0 && bytes > c.maxBytes
+		if !overEntries && !overBytes {
+			return
+		}
+
+		c.evictMu.Lock()
+		victim, ok := c.evictor.victim(key)
+		c.evictMu.Unlock()
+		if !ok {
+			return
+		}
+		c.removeLocked(victim, ReasonCapacity)
+		c.evictions.Add(1)
+	}
+}
+
+// removeLocked deletes an entry and its bookkeeping, and queues the eviction
+// callbacks. Deletions and capacity evictions are logged to the write-ahead
+// log; expirations are not, since replay drops expired entries anyway. It
+// must be called with c.mu held.
+func (c *TTLCache[K, V]) removeLocked(key K, reason EvictionReason) {
+	item, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	delete(c.cache, key)
+	heap.Remove(&c.expiry, item.index)
+	c.recordEvictionLocked(key, item.Value, reason)
+	c.bytes -= item.size
+
+	if c.evictor != nil {
+		c.evictMu.Lock()
+		c.evictor.remove(key)
+		c.evictMu.Unlock()
+	}
+	if reason == ReasonExpired {
+		c.expirations.Add(1)
+	}
+	if reason == ReasonDeleted || reason == ReasonCapacity {
+		c.logDeleteLocked(key)
+	}
+}
+
+// Delete removes key from the cache. It reports whether a live entry was
+// removed; an entry that had already expired is removed as expired and
+// Delete returns false.
+func (c *TTLCache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return false
 	}
 
-	expiresAt := time.Now().Add(effectiveTTL).UnixMilli()
+	item, ok := c.cache[key]
+	if !ok {
+		return false
+	}
+	if c.clock.Now().UnixMilli() >= item.ExpiresAt {
+		c.removeLocked(key, ReasonExpired)
+		return false
+	}
+	c.removeLocked(key, ReasonDeleted)
+	return true
+}
 
-	c.cache[key] = CacheEntry{
-		Value:     value,
-		ExpiresAt: expiresAt,
+// Expire removes key as if it had expired, so OnEvicted callbacks and
+// subscribers see ReasonExpired rather than ReasonDeleted. It reports whether
+// an entry was removed. Replication uses it for a write that arrives after
+// its expiry time.
+func (c *TTLCache[K, V]) Expire(key K) bool {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return false
+	}
+	if _, ok := c.cache[key]; !ok {
+		return false
 	}
+	c.removeLocked(key, ReasonExpired)
+	return true
+}
+
+// Evictions returns the number of entries removed to stay within MaxEntries
+// or MaxBytes. A steadily rising count means the cache is undersized.
+func (c *TTLCache[K, V]) Evictions() uint64 {
+	return c.evictions.Load()
 }
 
 // Get retrieves a value from the cache. It returns the value and a boolean
-// indicating whether the key was found. Expired keys are removed by the
-// background janitor, so Get doesn't need to check for expiration.
-func (c *TTLCache) Get(key string) (float64, bool) {
+// indicating whether the key was found. On a miss the zero value of V is returned.
+// An entry whose ExpiresAt has passed is treated as a miss even if the
+// background janitor has not removed it yet, so a stale value is never served.
+func (c *TTLCache[K, V]) Get(key K) (V, bool) {
+	value, _, found := c.GetWithExpiry(key)
+	return value, found
+}
+
+// GetWithExpiry retrieves a value from the cache together with its remaining
+// TTL. It returns the value, the time left until the entry expires and a
+// boolean indicating whether a live entry was found. Reading an entry past
+// its soft TTL starts a background refresh, see Config.SoftTTL.
+func (c *TTLCache[K, V]) GetWithExpiry(key K) (V, time.Duration, bool) {
+	value, remaining, found, stale := c.lookup(key)
+	c.recordLookup(found)
+	// The refresh is started after lookup has released mu, because it takes loadMu.
+	if stale {
+		c.refresh(key)
+	}
+	return value, remaining, found
+}
+
+// lookup returns a live entry's value and remaining TTL, and whether it is
+// past its soft TTL. It records the read for eviction and refresh-ahead.
+func (c *TTLCache[K, V]) lookup(key K) (value V, remaining time.Duration, found, stale bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return c.lookupLocked(key, c.clock.Now().UnixMilli())
+}
 
+// lookupLocked is lookup for a caller that holds c.mu (read or write) and
+// has already read the clock.
+func (c *TTLCache[K, V]) lookupLocked(key K, now int64) (value V, remaining time.Duration, found, stale bool) {
 	entry, found := c.cache[key]
-	if !found {
-		return 0, false
+	if !found || now >= entry.ExpiresAt {
+		return value, 0, false, false
+	}
+
+	c.accessLocked(key)
+	entry.read.Store(true)
+	remaining = time.Duration(entry.ExpiresAt-now) * time.Millisecond
+	return entry.Value, remaining, true, now >= entry.staleAt
+}
+
+// accessLocked records a use of key for the eviction policy, as a read or a
+// Touch. It must be called with c.mu held, for reading or writing.
+func (c *TTLCache[K, V]) accessLocked(key K) {
+	if c.evictor != nil {
+		c.evictMu.Lock()
+		c.evictor.touch(key)
+		c.evictMu.Unlock()
 	}
-	return entry.Value, true
 }
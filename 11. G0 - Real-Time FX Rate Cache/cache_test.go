@@ -0,0 +1,326 @@
+package fxcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestCache returns a cache driven by a manual clock. The janitor interval is
+// as long as the default TTL so that it only runs when a test advances the
+// clock by a full hour.
+func newTestCache(t *testing.T) (*FXRateCache, *ManualClock) {
+	t.Helper()
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Hour,
+		JanitorInterval: time.Hour,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	t.Cleanup(cache.StopJanitor)
+	return cache, clock
+}
+
+// stored reports whether key is still held in the cache's map, whether or not
+// it has expired.
+func stored(c *FXRateCache, key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.cache[key]
+	return ok
+}
+
+func TestGetTreatsExpiredEntryAsMiss(t *testing.T) {
+	cache, clock := newTestCache(t)
+
+	cache.Set("USD/THB", 36.5, 100*time.Millisecond)
+
+	clock.Advance(99 * time.Millisecond)
+	if val, ok := cache.Get("USD/THB"); !ok || val != 36.5 {
+		t.Fatalf("Get before expiry = (%v, %v), want (36.5, true)", val, ok)
+	}
+
+	clock.Advance(1 * time.Millisecond)
+	if val, ok := cache.Get("USD/THB"); ok || val != 0 {
+		t.Fatalf("Get at expiry = (%v, %v), want (0, false)", val, ok)
+	}
+
+	// The janitor has not run, so the entry is still stored but must not be served.
+	if !stored(cache, "USD/THB") {
+		t.Fatal("entry was removed without a janitor sweep")
+	}
+}
+
+func TestGetWithExpiry(t *testing.T) {
+	cache, clock := newTestCache(t)
+
+	if _, _, ok := cache.GetWithExpiry("EUR/USD"); ok {
+		t.Fatal("GetWithExpiry on missing key reported found")
+	}
+
+	cache.Set("EUR/USD", 1.08, 500*time.Millisecond)
+	clock.Advance(200 * time.Millisecond)
+
+	val, ttl, ok := cache.GetWithExpiry("EUR/USD")
+	if !ok || val != 1.08 {
+		t.Fatalf("GetWithExpiry = (%v, %v, %v), want (1.08, _, true)", val, ttl, ok)
+	}
+	if ttl != 300*time.Millisecond {
+		t.Fatalf("remaining TTL = %v, want 300ms", ttl)
+	}
+
+	clock.Advance(300 * time.Millisecond)
+	if val, ttl, ok := cache.GetWithExpiry("EUR/USD"); ok || val != 0 || ttl != 0 {
+		t.Fatalf("GetWithExpiry after expiry = (%v, %v, %v), want (0, 0, false)", val, ttl, ok)
+	}
+}
+
+func TestSetResetsExpiry(t *testing.T) {
+	cache, clock := newTestCache(t)
+
+	cache.Set("JPY/THB", 0.23, 300*time.Millisecond)
+	clock.Advance(200 * time.Millisecond)
+	cache.Set("JPY/THB", 0.24, 300*time.Millisecond)
+	clock.Advance(200 * time.Millisecond)
+
+	val, ttl, ok := cache.GetWithExpiry("JPY/THB")
+	if !ok || val != 0.24 || ttl != 100*time.Millisecond {
+		t.Fatalf("GetWithExpiry = (%v, %v, %v), want (0.24, 100ms, true)", val, ttl, ok)
+	}
+}
+
+func TestJanitorRemovesExpiredEntries(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Second,
+		JanitorInterval: 100 * time.Millisecond,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	start := clock.Now()
+	cache.Set("AUD/USD", 0.66, 150*time.Millisecond)
+	cache.Set("NZD/USD", 0.61)
+
+	// The janitor sleeps straight to the first deadline at 150ms. Once the
+	// sweep is done it re-arms its timer, which BlockUntil waits for.
+	clock.Advance(150 * time.Millisecond)
+	clock.BlockUntil(1)
+
+	if stored(cache, "AUD/USD") {
+		t.Fatal("janitor did not remove expired AUD/USD")
+	}
+	if !stored(cache, "NZD/USD") {
+		t.Fatal("janitor removed live NZD/USD")
+	}
+
+	cache.mu.RLock()
+	next := cache.janitorAt
+	cache.mu.RUnlock()
+	if want := start.Add(time.Second).UnixMilli(); next != want {
+		t.Fatalf("janitor armed for %v, want the NZD/USD deadline %v", next, want)
+	}
+}
+
+func TestJanitorHonorsMinimumInterval(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Second,
+		JanitorInterval: 100 * time.Millisecond,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	cache.Set("AUD/USD", 0.66, 10*time.Millisecond)
+	cache.Set("NZD/USD", 0.61, 60*time.Millisecond)
+
+	// Both expire before the first allowed sweep at 100ms.
+	clock.Advance(99 * time.Millisecond)
+	if !stored(cache, "AUD/USD") {
+		t.Fatal("janitor swept before the minimum interval")
+	}
+	clock.Advance(1 * time.Millisecond)
+	clock.BlockUntil(1)
+	if stored(cache, "AUD/USD") || stored(cache, "NZD/USD") {
+		t.Fatal("janitor did not remove both entries in one sweep")
+	}
+}
+
+func TestManualClockTimer(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	timer := clock.NewTimer(time.Second)
+
+	done := make(chan time.Time)
+	go func() { done <- <-timer.C() }()
+	clock.Advance(time.Second)
+	if got := <-done; !got.Equal(time.Unix(1, 0)) {
+		t.Fatalf("timer fired at %v, want %v", got, time.Unix(1, 0))
+	}
+
+	timer.Reset(time.Second)
+	if !timer.Stop() {
+		t.Fatal("Stop on an armed timer returned false")
+	}
+	// Advancing past a stopped timer must not block.
+	clock.Advance(2 * time.Second)
+	if got := clock.Now(); !got.Equal(time.Unix(3, 0)) {
+		t.Fatalf("Now() = %v, want %v", got, time.Unix(3, 0))
+	}
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	tests := []struct {
+		policy EvictionPolicy
+		want   []string // keys left after the access pattern below
+	}{
+		{EvictLRU, []string{"EUR/USD", "JPY/THB", "USD/THB"}},
+		{EvictLFU, []string{"GBP/USD", "JPY/THB", "USD/THB"}},
+		{EvictFIFO, []string{"EUR/USD", "GBP/USD", "JPY/THB"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			cache, err := NewTTLCacheWithConfig[string, float64](Config{
+				DefaultTTL:     time.Hour,
+				MaxEntries:     3,
+				EvictionPolicy: tt.policy,
+				Clock:          NewManualClock(time.Unix(0, 0)),
+			})
+			if err != nil {
+				t.Fatalf("NewTTLCacheWithConfig: %v", err)
+			}
+			defer cache.StopJanitor()
+
+			cache.Set("USD/THB", 36.5)
+			cache.Set("GBP/USD", 1.25)
+			cache.Set("EUR/USD", 1.08)
+			// GBP/USD is used most often, EUR/USD least recently among the
+			// entries used twice, and USD/THB most recently.
+			cache.Get("GBP/USD")
+			cache.Get("GBP/USD")
+			cache.Get("EUR/USD")
+			cache.Get("USD/THB")
+			cache.Set("JPY/THB", 0.23)
+
+			for _, key := range []string{"EUR/USD", "GBP/USD", "JPY/THB", "USD/THB"} {
+				want := false
+				for _, k := range tt.want {
+					want = want || k == key
+				}
+				if got := stored(cache, key); got != want {
+					t.Errorf("stored(%q) = %v, want %v", key, got, want)
+				}
+			}
+			if got := cache.Evictions(); got != 1 {
+				t.Errorf("Evictions() = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestMaxBytesEviction(t *testing.T) {
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL: time.Hour,
+		MaxBytes:   250,
+		Sizer:      func(key, value any) int64 { return 100 },
+		Clock:      NewManualClock(time.Unix(0, 0)),
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	cache.Set("USD/THB", 36.5)
+	cache.Set("EUR/USD", 1.08)
+	cache.Set("EUR/USD", 1.09) // overwriting must not evict
+	if got := cache.Evictions(); got != 0 {
+		t.Fatalf("Evictions() after overwrite = %d, want 0", got)
+	}
+	cache.Set("GBP/USD", 1.25)
+	if stored(cache, "USD/THB") || cache.Evictions() != 1 {
+		t.Fatalf("expected USD/THB to be evicted once, evictions = %d", cache.Evictions())
+	}
+}
+
+func TestOnEvictedReasons(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Second,
+		JanitorInterval: 100 * time.Millisecond,
+		MaxEntries:      2,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	type event struct {
+		key    string
+		value  float64
+		reason EvictionReason
+	}
+	var events []event
+	cache.OnEvicted(func(key string, value float64, reason EvictionReason) {
+		// Calling back into the cache must not deadlock.
+		cache.Get(key)
+		events = append(events, event{key, value, reason})
+	})
+
+	cache.Set("USD/THB", 36.5)
+	cache.Set("USD/THB", 36.6) // replaced
+	cache.Set("EUR/USD", 1.08, 150*time.Millisecond)
+	cache.Set("GBP/USD", 1.25)            // evicts USD/THB for capacity
+	cache.Delete("GBP/USD")               // deleted
+	clock.Advance(150 * time.Millisecond) // EUR/USD expires
+	clock.BlockUntil(1)                   // waits for the sweep
+
+	want := []event{
+		{"USD/THB", 36.5, ReasonReplaced},
+		{"USD/THB", 36.6, ReasonCapacity},
+		{"GBP/USD", 1.25, ReasonDeleted},
+		{"EUR/USD", 1.08, ReasonExpired},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %v, want %v", len(events), events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %v, want %v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestOnSet(t *testing.T) {
+	cache, clock := newTestCache(t)
+
+	var log []string
+	cache.OnSet(func(key string, value float64, expiresAt time.Time) {
+		log = append(log, fmt.Sprintf("set %s=%v until +%v", key, value, expiresAt.Sub(clock.Now())))
+	})
+	cache.OnEvicted(func(key string, value float64, reason EvictionReason) {
+		log = append(log, fmt.Sprintf("%s %s=%v", reason, key, value))
+	})
+
+	cache.Set("USD/THB", 36.5, time.Minute)
+	cache.Set("USD/THB", 36.6)
+	cache.SetIfAbsent("EUR/USD", 1.08)
+
+	want := []string{
+		"set USD/THB=36.5 until +1m0s",
+		"replaced USD/THB=36.5",
+		"set USD/THB=36.6 until +1h0m0s",
+		"set EUR/USD=1.08 until +1h0m0s",
+	}
+	if fmt.Sprint(log) != fmt.Sprint(want) {
+		t.Fatalf("notifications = %q, want %q", log, want)
+	}
+}
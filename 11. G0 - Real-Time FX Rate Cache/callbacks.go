@@ -0,0 +1,136 @@
+package fxcache
+
+import (
+	"fmt"
+	"time"
+)
+
+// EvictionReason tells an OnEvicted callback why an entry left the cache.
+type EvictionReason int
+
+const (
+	// ReasonExpired means the entry's TTL passed.
+	ReasonExpired EvictionReason = iota
+	// ReasonCapacity means the entry was evicted to respect MaxEntries or MaxBytes.
+	ReasonCapacity
+	// ReasonDeleted means the entry was removed explicitly, e.g. with Delete.
+	ReasonDeleted
+	// ReasonReplaced means the entry was overwritten by a new value for the same key.
+	ReasonReplaced
+)
+
+// String returns the reason name.
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	default:
+		return fmt.Sprintf("EvictionReason(%d)", int(r))
+	}
+}
+
+// EvictionFunc is called with the key and the old value of an entry that
+// left the cache.
+type EvictionFunc[K comparable, V any] func(key K, value V, reason EvictionReason)
+
+// SetFunc is called with the key, the new value and the expiry time of an
+// entry that was stored.
+type SetFunc[K comparable, V any] func(key K, value V, expiresAt time.Time)
+
+// notification is an eviction or a set waiting to be reported to the
+// callbacks. Both kinds share one queue so they are reported in order.
+type notification[K comparable, V any] struct {
+	key   K
+	value V
+	// set is true for a stored entry, which expires at expiresAt (Unix
+	// milliseconds) and replaced a live entry if replaced is true. It is
+	// false for an eviction with the given reason.
+	set       bool
+	replaced  bool
+	expiresAt int64
+	reason    EvictionReason
+}
+
+// OnEvicted registers fn to be called whenever an entry expires, is evicted
+// for capacity, is deleted or is overwritten. Several callbacks can be
+// registered; they run in registration order.
+//
+// Callbacks run synchronously on the goroutine that caused the eviction (the
+// janitor for expiry), but only after the cache lock has been released, so a
+// callback may safely call back into the cache.
+func (c *TTLCache[K, V]) OnEvicted(fn EvictionFunc[K, V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvicted = append(c.onEvicted, fn)
+}
+
+// OnSet registers fn to be called whenever a value is stored, by Set or any
+// other method that writes one (including loads and refreshes). Overwriting
+// an entry reports the old value to OnEvicted first. OnSet callbacks run
+// under the same rules as OnEvicted callbacks.
+func (c *TTLCache[K, V]) OnSet(fn SetFunc[K, V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onSet = append(c.onSet, fn)
+}
+
+// recordEvictionLocked queues an eviction for the callbacks and subscribers.
+// It must be called with c.mu held.
+func (c *TTLCache[K, V]) recordEvictionLocked(key K, value V, reason EvictionReason) {
+	if len(c.onEvicted) == 0 && len(c.subscribers) == 0 {
+		return
+	}
+	c.pending = append(c.pending, notification[K, V]{key: key, value: value, reason: reason})
+}
+
+// recordSetLocked queues a stored entry for the callbacks and subscribers. It
+// must be called with c.mu held.
+func (c *TTLCache[K, V]) recordSetLocked(key K, value V, expiresAt int64, replaced bool) {
+	if len(c.onSet) == 0 && len(c.subscribers) == 0 {
+		return
+	}
+	c.pending = append(c.pending, notification[K, V]{key: key, value: value, set: true, replaced: replaced, expiresAt: expiresAt})
+}
+
+// unlockAndNotify releases c.mu and then publishes the notifications queued
+// while it was held to the subscribers, runs the callbacks for them and
+// reports the errors met meanwhile to OnError.
+func (c *TTLCache[K, V]) unlockAndNotify() {
+	pending, onEvicted, onSet, subs := c.pending, c.onEvicted, c.onSet, c.subscribers
+	failed := c.failed
+	c.pending, c.failed = nil, nil
+	if len(pending) > 0 && len(subs) > 0 {
+		// Taking publishMu before releasing c.mu orders the events of
+		// concurrent writers. Publishing never calls back into the cache, so
+		// it cannot deadlock the way a callback could.
+		c.publishMu.Lock()
+		c.mu.Unlock()
+		if dropped := publish(subs, pending); dropped > 0 {
+			c.droppedEvents.Add(dropped)
+		}
+		c.publishMu.Unlock()
+	} else {
+		c.mu.Unlock()
+	}
+
+	for _, n := range pending {
+		if n.set {
+			for _, fn := range onSet {
+				fn(n.key, n.value, time.UnixMilli(n.expiresAt))
+			}
+			continue
+		}
+		for _, fn := range onEvicted {
+			fn(n.key, n.value, n.reason)
+		}
+	}
+	for _, err := range failed {
+		c.onError(err)
+	}
+}
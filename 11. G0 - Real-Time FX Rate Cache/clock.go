@@ -0,0 +1,185 @@
+package fxcache
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by TTLCache. The default is the system
+// clock; tests can pass a ManualClock to control expiry deterministically.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer returns a Timer that fires once after d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer delivers a single tick after a delay, like time.Timer.
+type Timer interface {
+	// C returns the channel on which the tick is delivered.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It reports whether the timer
+	// was active.
+	Stop() bool
+	// Reset changes the timer to fire after d. No tick from before the
+	// Reset is received after it returns. It reports whether the timer was
+	// active.
+	Reset(d time.Duration) bool
+}
+
+// SystemClock is a Clock backed by the time package.
+type SystemClock struct{}
+
+// Now returns time.Now().
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer returns a Timer backed by time.NewTimer.
+func (SystemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+// systemTimer adapts time.Timer. Since Go 1.23, Stop and Reset discard a
+// pending tick, which is the behavior Timer documents.
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (s systemTimer) C() <-chan time.Time        { return s.t.C }
+func (s systemTimer) Stop() bool                 { return s.t.Stop() }
+func (s systemTimer) Reset(d time.Duration) bool { return s.t.Reset(d) }
+
+// ManualClock is a Clock whose time only moves when Advance or Set is called.
+// It is safe for concurrent use.
+type ManualClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	timers  map[*manualTimer]struct{}
+	waiting int // number of armed timers
+}
+
+// NewManualClock returns a ManualClock starting at the given time.
+func NewManualClock(start time.Time) *ManualClock {
+	m := &ManualClock{now: start, timers: make(map[*manualTimer]struct{})}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// Now returns the clock's current time.
+func (m *ManualClock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// NewTimer returns a Timer that fires once the clock reaches Now()+d.
+func (m *ManualClock) NewTimer(d time.Duration) Timer {
+	t := &manualTimer{clock: m, c: make(chan time.Time)}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d and fires every timer that became due.
+// Advance blocks until each fired tick has been received or its timer has
+// been stopped or reset.
+func (m *ManualClock) Advance(d time.Duration) {
+	m.mu.Lock()
+	m.moveLocked(m.now.Add(d))
+}
+
+// Set moves the clock to t and fires due timers as described for Advance.
+func (m *ManualClock) Set(t time.Time) {
+	m.mu.Lock()
+	m.moveLocked(t)
+}
+
+// BlockUntil waits until at least n timers are armed. Tests use it to wait
+// for a goroutine to finish handling a tick and go back to sleep.
+func (m *ManualClock) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for m.waiting < n {
+		m.cond.Wait()
+	}
+}
+
+// moveLocked sets the time and fires due timers. It is called with m.mu held
+// and releases it before delivering ticks, because the reader of a tick may
+// call Now.
+func (m *ManualClock) moveLocked(now time.Time) {
+	m.now = now
+
+	type firing struct {
+		t      *manualTimer
+		cancel chan struct{}
+	}
+	var due []firing
+	for t := range m.timers {
+		if !now.Before(t.at) {
+			due = append(due, firing{t, t.cancel})
+			m.disarmLocked(t)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, f := range due {
+		select {
+		case f.t.c <- now:
+		case <-f.cancel:
+		}
+	}
+}
+
+// disarmLocked removes t from the armed timers. It must be called with m.mu held.
+func (m *ManualClock) disarmLocked(t *manualTimer) bool {
+	if _, ok := m.timers[t]; !ok {
+		return false
+	}
+	delete(m.timers, t)
+	m.waiting--
+	return true
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	c     chan time.Time
+	// at and cancel are guarded by clock.mu. cancel is closed when the
+	// timer is stopped or reset, aborting a tick that is being delivered.
+	at     time.Time
+	cancel chan struct{}
+}
+
+func (t *manualTimer) C() <-chan time.Time { return t.c }
+
+func (t *manualTimer) Stop() bool {
+	m := t.clock
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return t.stopLocked()
+}
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	m := t.clock
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active := t.stopLocked()
+	t.at = m.now.Add(d)
+	t.cancel = make(chan struct{})
+	m.timers[t] = struct{}{}
+	m.waiting++
+	m.cond.Broadcast()
+	return active
+}
+
+// stopLocked disarms the timer and aborts a tick in delivery. It must be
+// called with clock.mu held.
+func (t *manualTimer) stopLocked() bool {
+	if t.cancel != nil {
+		close(t.cancel)
+		t.cancel = nil
+	}
+	return t.clock.disarmLocked(t)
+}
@@ -0,0 +1,280 @@
+package fxcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCloseIsIdempotent(t *testing.T) {
+	cache, _ := newTestCache(t)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cache.Close(context.Background()); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := cache.Close(context.Background()); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	// StopJanitor used to panic when called twice; the cleanup of
+	// newTestCache calls it once more, also after Close.
+	cache.StopJanitor()
+}
+
+func TestStopJanitorFromJanitorCallback(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Second,
+		JanitorInterval: time.Second,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	stopped := make(chan struct{})
+	// The janitor runs this callback, so waiting for the janitor here
+	// would never return.
+	cache.OnEvicted(func(string, float64, EvictionReason) {
+		cache.StopJanitor()
+		close(stopped)
+	})
+	cache.Set("USD/THB", 36.5)
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("StopJanitor called by the janitor did not return")
+	}
+
+	// Only the janitor stopped: writes still work, and expired entries are
+	// hidden but no longer swept.
+	cache.Set("EUR/USD", 1.08)
+	if val, ok := cache.Get("EUR/USD"); !ok || val != 1.08 {
+		t.Fatalf("Get after StopJanitor = (%v, %v), want (1.08, true)", val, ok)
+	}
+	clock.Advance(time.Second)
+	if _, ok := cache.Get("EUR/USD"); ok {
+		t.Fatal("EUR/USD still returned after its TTL")
+	}
+	if !stored(cache, "EUR/USD") {
+		t.Fatal("EUR/USD swept after StopJanitor")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := cache.Close(ctx); err != nil {
+		t.Fatalf("Close after StopJanitor: %v", err)
+	}
+}
+
+func TestClosedCacheRejectsOperations(t *testing.T) {
+	cache, _ := newTestCache(t)
+	cache.Set("USD/THB", 36.5)
+	var snap bytes.Buffer
+	if err := cache.SaveSnapshot(&snap); err != nil {
+		t.Fatal(err)
+	}
+	cache.Close(context.Background())
+
+	// Reads still see the entries held at Close.
+	if val, ok := cache.Get("USD/THB"); !ok || val != 36.5 {
+		t.Errorf("Get after Close = (%v, %v), want (36.5, true)", val, ok)
+	}
+
+	// Writes are ignored.
+	cache.Set("EUR/USD", 1.08)
+	cache.SetMany(map[string]float64{"GBP/USD": 1.27})
+	if cache.Has("EUR/USD") || cache.Has("GBP/USD") {
+		t.Error("Set after Close stored a value")
+	}
+	if cache.Delete("USD/THB") || cache.Touch("USD/THB") || cache.SetIfAbsent("USD/JPY", 151) ||
+		cache.CompareAndSwap("USD/THB", 36.5, 36.6) || cache.SetUntil("USD/JPY", 151, time.Now().Add(time.Hour)) {
+		t.Error("a conditional write after Close reported success")
+	}
+	cache.Clear()
+	if !cache.Has("USD/THB") {
+		t.Error("Clear after Close removed an entry")
+	}
+
+	// Methods with an error result return ErrClosed.
+	loader := func(context.Context, string) (float64, error) { return 1, nil }
+	if _, err := cache.GetOrLoad(context.Background(), "USD/SGD", loader); !errors.Is(err, ErrClosed) {
+		t.Errorf("GetOrLoad after Close: err = %v, want ErrClosed", err)
+	}
+	if _, _, err := cache.Subscribe(nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe after Close: err = %v, want ErrClosed", err)
+	}
+	if err := cache.LoadSnapshot(&snap); !errors.Is(err, ErrClosed) {
+		t.Errorf("LoadSnapshot after Close: err = %v, want ErrClosed", err)
+	}
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	cache, _ := newTestCache(t)
+	events, cancel, err := cache.Subscribe(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Close(context.Background())
+	if _, ok := <-events; ok {
+		t.Error("subscription channel still open after Close")
+	}
+	// Canceling a subscription ended by Close is harmless.
+	cancel()
+}
+
+func TestCloseWaitsForRefreshes(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Hour,
+		JanitorInterval: time.Hour,
+		SoftTTL:         time.Second,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	cache.SetLoader(func(ctx context.Context, key string) (float64, error) {
+		close(started)
+		// The loader ignores ctx, so only release lets it return.
+		<-release
+		return 36.6, nil
+	})
+	cache.Set("USD/THB", 36.5)
+	clock.Advance(time.Second)
+	cache.Get("USD/THB")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := cache.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close with a refresh in flight: err = %v, want DeadlineExceeded", err)
+	}
+	close(release)
+	if err := cache.Close(context.Background()); err != nil {
+		t.Fatalf("Close after the refresh returned: %v", err)
+	}
+	// The refresh finished after Close, so its value was not stored.
+	if val, _ := cache.Get("USD/THB"); val != 36.5 {
+		t.Errorf("Get = %v, want 36.5", val)
+	}
+}
+
+func TestCloseCancelsRefreshContext(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Hour,
+		JanitorInterval: time.Hour,
+		SoftTTL:         time.Second,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+
+	started := make(chan struct{})
+	cache.SetLoader(func(ctx context.Context, key string) (float64, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	cache.Set("USD/THB", 36.5)
+	clock.Advance(time.Second)
+	cache.Get("USD/THB")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := cache.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestCloseLeavesNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	dir := t.TempDir()
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:       time.Second,
+		JanitorInterval:  10 * time.Millisecond,
+		SoftTTL:          10 * time.Millisecond,
+		SnapshotPath:     filepath.Join(dir, "fx.snapshot"),
+		SnapshotInterval: 10 * time.Millisecond,
+		WALPath:          filepath.Join(dir, "fx.wal"),
+		WALSync:          WALSyncInterval,
+		WALSyncInterval:  10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	sharded, err := NewShardedTTLCache[string, float64](4, Config{DefaultTTL: time.Second})
+	if err != nil {
+		t.Fatalf("NewShardedTTLCache: %v", err)
+	}
+
+	cache.SetLoader(func(ctx context.Context, key string) (float64, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if _, _, err := sharded.Subscribe(nil); err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"USD/THB", "EUR/USD", "GBP/USD"}
+	for _, key := range keys {
+		sharded.Set(key, 1)
+	}
+	cache.SetMany(map[string]float64{"USD/THB": 1, "EUR/USD": 1, "GBP/USD": 1})
+	// Let the entries go stale and start refreshes that only end on Close.
+	time.Sleep(20 * time.Millisecond)
+	cache.GetMany(keys)
+	// Nobody reads this subscription, so the writer below blocks until
+	// Close ends it.
+	if _, _, err := cache.Subscribe(nil, SubscribeOptions{Overflow: OverflowBlock, Buffer: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var writer sync.WaitGroup
+	writer.Add(1)
+	go func() {
+		defer writer.Done()
+		for _, key := range keys {
+			cache.Set(key, 2)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := cache.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := sharded.Close(ctx); err != nil {
+		t.Fatalf("sharded Close: %v", err)
+	}
+	writer.Wait()
+
+	// Goroutines that have returned may take a moment to be accounted for.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines after Close, %d before:\n%s", runtime.NumGoroutine(), before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
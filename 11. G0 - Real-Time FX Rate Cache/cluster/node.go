@@ -0,0 +1,456 @@
+// Package cluster replicates an FX rate cache across several nodes so that
+// they agree on rates.
+//
+// Every node keeps a full copy of the rates and serves reads locally. Writes
+// are ordered by the key's owner, chosen with a consistent-hash Ring: a write
+// on another node is applied locally and forwarded to the owner, and the
+// owner applies it and replicates it to every other node. Replication is
+// asynchronous over HTTP, with one ordered queue per peer, so a write never
+// waits for the network. Entries carry their absolute expiry time, so all
+// copies expire at the same moment.
+//
+// Node.Handler lets any client that reaches it overwrite rates. Serve it on
+// a private network only, and set Config.Secret so that only nodes sharing
+// the secret are accepted.
+//
+// The nodes' clocks should be synchronized (e.g. with NTP), since expiry is
+// absolute. There is no anti-entropy: a node that was unreachable for longer
+// than its peers' queues could hold misses the dropped writes until the keys
+// are written again, so it should start from a snapshot of a peer.
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+)
+
+// Defaults for Config.
+const (
+	DefaultQueueSize     = 1024
+	DefaultRetryInterval = 100 * time.Millisecond
+)
+
+// maxBatch is the largest number of operations sent in one request.
+const maxBatch = 256
+
+// replicatePath is the route of Node.Handler.
+const replicatePath = "/cluster/v1/ops"
+
+// secretHeader carries Config.Secret on replication requests.
+const secretHeader = "X-Cluster-Secret"
+
+// maxBodyBytes bounds the request body read by Node.Handler, far above a
+// batch of maxBatch operations on currency-pair keys.
+const maxBodyBytes = 4 << 20
+
+// Config holds the settings used by NewNode. Zero values fall back to the
+// defaults.
+type Config struct {
+	// Self is this node's base URL, e.g. "http://10.0.0.1:8080". It must be
+	// one of Peers.
+	Self string
+	// Peers are the base URLs of every node in the cluster, including Self.
+	// All nodes must be given the same list.
+	Peers []string
+	// VirtualNodes is the number of ring points per node.
+	VirtualNodes int
+	// QueueSize is the number of operations queued per peer. Operations for
+	// a peer whose queue is full are dropped and reported to OnError.
+	QueueSize int
+	// RetryInterval is the wait before resending to a peer that failed.
+	RetryInterval time.Duration
+	// Clock is used to turn TTLs into absolute expiry times. It defaults to
+	// fxcache.SystemClock and should be the cache's clock.
+	Clock fxcache.Clock
+	// Client sends the operations. It defaults to http.DefaultClient.
+	Client *http.Client
+	// OnError receives replication errors. It defaults to logging them.
+	OnError func(error)
+	// Secret, if set, is sent with every replication request, and requests
+	// to Handler without it are rejected. All nodes must share it.
+	Secret string
+}
+
+// op is one replicated write. ExpiresAt is in Unix milliseconds.
+type op struct {
+	Op        string  `json:"op"`
+	Key       string  `json:"key"`
+	Value     float64 `json:"value,omitempty"`
+	ExpiresAt int64   `json:"expires_at,omitempty"`
+}
+
+const (
+	opSet = "set"
+	opDel = "del"
+)
+
+// batch is the JSON body of a replication request.
+type batch struct {
+	From string `json:"from"`
+	Ops  []op   `json:"ops"`
+}
+
+// peer is the outgoing queue to one other node.
+type peer struct {
+	url   string
+	queue chan op
+	// pending counts operations queued or being sent, for Flush.
+	pending atomic.Int64
+}
+
+// Node replicates the writes made through it to the other nodes of a
+// cluster, and applies the writes it receives on Handler to its cache.
+type Node struct {
+	cache   *fxcache.FXRateCache
+	self    string
+	ring    *Ring
+	peers   map[string]*peer
+	clock   fxcache.Clock
+	client  *http.Client
+	retry   time.Duration
+	onError func(error)
+	secret  string
+
+	// mu makes applying a write and queueing it one step, so the owner
+	// queues writes in the order it applied them. closed is set under it by
+	// Close, after which nothing more is queued.
+	mu     sync.Mutex
+	closed bool
+
+	stop      chan struct{}
+	senders   sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewNode returns a node that replicates cache. It starts one sender
+// goroutine per peer; call Close to stop them.
+func NewNode(cache *fxcache.FXRateCache, cfg Config) (*Node, error) {
+	if cfg.Self == "" || !slices.Contains(cfg.Peers, cfg.Self) {
+		return nil, fmt.Errorf("cluster Self %q must be one of Peers", cfg.Self)
+	}
+	if cfg.QueueSize < 0 || cfg.RetryInterval < 0 {
+		return nil, errors.New("cluster queue size and retry interval must not be negative")
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = DefaultRetryInterval
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = fxcache.SystemClock{}
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(err error) { log.Printf("cluster: %v", err) }
+	}
+
+	n := &Node{
+		cache:   cache,
+		self:    cfg.Self,
+		ring:    NewRing(cfg.VirtualNodes, cfg.Peers...),
+		peers:   make(map[string]*peer),
+		clock:   cfg.Clock,
+		client:  cfg.Client,
+		retry:   cfg.RetryInterval,
+		onError: cfg.OnError,
+		secret:  cfg.Secret,
+		stop:    make(chan struct{}),
+	}
+	for _, url := range n.ring.Nodes() {
+		if url == n.self {
+			continue
+		}
+		p := &peer{url: url, queue: make(chan op, cfg.QueueSize)}
+		n.peers[url] = p
+		n.senders.Add(1)
+		go n.send(p)
+	}
+	return n, nil
+}
+
+// Self returns the base URL of the node.
+func (n *Node) Self() string {
+	return n.self
+}
+
+// Owner returns the node that orders the writes of key.
+func (n *Node) Owner(key string) string {
+	return n.ring.Owner(key)
+}
+
+// Get returns the local copy of key's rate.
+func (n *Node) Get(key string) (float64, bool) {
+	return n.cache.Get(key)
+}
+
+// Set stores the rate of key with the optional ttl, or the cache's default
+// TTL, and replicates it. The expiry time is fixed here, so every copy
+// expires at the same moment.
+func (n *Node) Set(key string, value float64, ttl ...time.Duration) {
+	n.write(op{Op: opSet, Key: key, Value: value, ExpiresAt: n.expiresAt(ttl)})
+}
+
+// SetMany stores and replicates several rates that share one expiry time.
+func (n *Node) SetMany(entries map[string]float64, ttl ...time.Duration) {
+	expiresAt := n.expiresAt(ttl)
+	for key, value := range entries {
+		n.write(op{Op: opSet, Key: key, Value: value, ExpiresAt: expiresAt})
+	}
+}
+
+// Delete removes key and replicates the deletion. It reports whether the
+// local copy held a live rate.
+func (n *Node) Delete(key string) bool {
+	return n.write(op{Op: opDel, Key: key})
+}
+
+// expiresAt returns the expiry time, in Unix milliseconds, of a write made
+// now with the optional ttl.
+func (n *Node) expiresAt(ttl []time.Duration) int64 {
+	d := n.cache.DefaultTTL()
+	if len(ttl) > 0 && ttl[0] > 0 {
+		d = ttl[0]
+	}
+	return n.clock.Now().Add(d).UnixMilli()
+}
+
+// write applies a write made here and sends it onwards. It returns the result
+// of apply.
+func (n *Node) write(o op) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ok := n.apply(o)
+	n.route(o)
+	return ok
+}
+
+// apply applies o to the cache. For a deletion it reports whether a live
+// rate was removed; a set always reports true.
+func (n *Node) apply(o op) bool {
+	switch o.Op {
+	case opSet:
+		// A write that expired on its way here still replaces the value
+		// held before it, which then expires with it.
+		if !n.cache.SetUntil(o.Key, o.Value, time.UnixMilli(o.ExpiresAt)) {
+			n.cache.Expire(o.Key)
+		}
+	case opDel:
+		return n.cache.Delete(o.Key)
+	}
+	return true
+}
+
+// route sends a write made or received here onwards: the owner replicates it
+// to every other node, and any other node forwards it to the owner.
+func (n *Node) route(o op) {
+	owner := n.ring.Owner(o.Key)
+	if owner != n.self {
+		n.enqueue(n.peers[owner], o)
+		return
+	}
+	for _, p := range n.peers {
+		n.enqueue(p, o)
+	}
+}
+
+// enqueue queues o for p. It must be called with n.mu held.
+func (n *Node) enqueue(p *peer, o op) {
+	if n.closed {
+		n.onError(fmt.Errorf("node is closed, dropping %s of %s for %s", o.Op, o.Key, p.url))
+		return
+	}
+	p.pending.Add(1)
+	select {
+	case p.queue <- o:
+	default:
+		p.pending.Add(-1)
+		n.onError(fmt.Errorf("queue to %s is full, dropping %s of %s", p.url, o.Op, o.Key))
+	}
+}
+
+// send delivers the queue of p in batches, retrying a failed batch until it
+// succeeds or the node is closed, so operations arrive in order.
+func (n *Node) send(p *peer) {
+	defer n.senders.Done()
+	for {
+		var ops []op
+		select {
+		case o := <-p.queue:
+			ops = append(ops, o)
+		case <-n.stop:
+			n.drop(p, nil)
+			return
+		}
+	fill:
+		for len(ops) < maxBatch {
+			select {
+			case o := <-p.queue:
+				ops = append(ops, o)
+			default:
+				break fill
+			}
+		}
+
+		for {
+			err := n.post(p.url, ops)
+			if err == nil {
+				break
+			}
+			n.onError(fmt.Errorf("replicate to %s: %w", p.url, err))
+			select {
+			case <-time.After(n.retry):
+			case <-n.stop:
+				n.drop(p, ops)
+				return
+			}
+		}
+		p.pending.Add(-int64(len(ops)))
+	}
+}
+
+// drop discards ops and the rest of the queue of p when the node is closed,
+// so that they no longer count as pending for Flush.
+func (n *Node) drop(p *peer, ops []op) {
+drain:
+	for {
+		select {
+		case o := <-p.queue:
+			ops = append(ops, o)
+		default:
+			break drain
+		}
+	}
+	if len(ops) == 0 {
+		return
+	}
+	p.pending.Add(-int64(len(ops)))
+	n.onError(fmt.Errorf("node closed, dropping %d operations for %s", len(ops), p.url))
+}
+
+func (n *Node) post(url string, ops []op) error {
+	body, err := json.Marshal(batch{From: n.self, Ops: ops})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-n.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+replicatePath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		req.Header.Set(secretHeader, n.secret)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler that receives operations from the other
+// nodes. Mount it at the root of the node's base URL, or on a mux next to
+// other routes; it only serves POST /cluster/v1/ops. It must not be exposed
+// to untrusted clients: without Config.Secret, anyone who reaches it can
+// overwrite rates. With it, requests lacking the secret get 401.
+func (n *Node) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+replicatePath, func(w http.ResponseWriter, r *http.Request) {
+		if n.secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(n.secret)) != 1 {
+			http.Error(w, "missing or wrong cluster secret", http.StatusUnauthorized)
+			return
+		}
+		var b batch
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&b); err != nil {
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		for _, o := range b.Ops {
+			if o.Op != opSet && o.Op != opDel {
+				http.Error(w, fmt.Sprintf("unknown op %q", o.Op), http.StatusBadRequest)
+				return
+			}
+		}
+		n.mu.Lock()
+		for _, o := range b.Ops {
+			n.apply(o)
+			// A forwarded write reaches its owner, which replicates it.
+			if n.ring.Owner(o.Key) == n.self {
+				n.route(o)
+			}
+		}
+		n.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// Flush waits until every queued operation has been delivered to its peer,
+// or ctx is done. Operations forwarded to an owner may still be on their way
+// to the other nodes when it returns.
+func (n *Node) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		idle := true
+		for _, p := range n.peers {
+			if p.pending.Load() > 0 {
+				idle = false
+			}
+		}
+		if idle {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close stops the senders. Operations not delivered yet, and those of later
+// writes, are dropped and reported to OnError; call Flush first to deliver
+// them. It does not stop the cache.
+func (n *Node) Close() error {
+	n.closeOnce.Do(func() {
+		n.mu.Lock()
+		n.closed = true
+		close(n.stop)
+		n.mu.Unlock()
+		n.senders.Wait()
+	})
+	return nil
+}
@@ -0,0 +1,352 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+)
+
+// testNode is a node served on a loopback listener. While down is set, its
+// handler answers 503 as if the node were unreachable.
+type testNode struct {
+	*Node
+	cache *fxcache.FXRateCache
+	srv   *httptest.Server
+	down  atomic.Bool
+}
+
+// newTestCluster starts n nodes on loopback that share clock.
+func newTestCluster(t *testing.T, n int, clock fxcache.Clock, onError func(error)) []*testNode {
+	t.Helper()
+	nodes := make([]*testNode, n)
+	var peers []string
+	for i := range nodes {
+		tn := &testNode{}
+		// The listener exists before the node, so every node knows every URL.
+		tn.srv = httptest.NewUnstartedServer(nil)
+		nodes[i] = tn
+		peers = append(peers, "http://"+tn.srv.Listener.Addr().String())
+	}
+	for i, tn := range nodes {
+		cache, err := fxcache.NewTTLCacheWithConfig[string, float64](fxcache.Config{
+			DefaultTTL:      time.Minute,
+			JanitorInterval: time.Minute,
+			Clock:           clock,
+		})
+		if err != nil {
+			t.Fatalf("NewTTLCacheWithConfig: %v", err)
+		}
+		node, err := NewNode(cache, Config{
+			Self:          peers[i],
+			Peers:         peers,
+			RetryInterval: 5 * time.Millisecond,
+			Clock:         clock,
+			OnError:       onError,
+		})
+		if err != nil {
+			t.Fatalf("NewNode: %v", err)
+		}
+		tn.Node, tn.cache = node, cache
+		handler := node.Handler()
+		tn.srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tn.down.Load() {
+				http.Error(w, "down", http.StatusServiceUnavailable)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+		tn.srv.Start()
+		t.Cleanup(func() {
+			node.Close()
+			tn.srv.Close()
+			cache.StopJanitor()
+		})
+	}
+	return nodes
+}
+
+// flush waits until the writes made so far have reached every node. Writes
+// forwarded to an owner are queued by the owner before it answers, so
+// flushing every node twice covers the second hop.
+func flush(t *testing.T, nodes []*testNode) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for range 2 {
+		for _, n := range nodes {
+			if err := n.Flush(ctx); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+		}
+	}
+}
+
+func TestNodeReplicatesSetsWithAbsoluteExpiry(t *testing.T) {
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	nodes := newTestCluster(t, 3, clock, nil)
+
+	keys := []string{"USD/THB", "EUR/USD", "GBP/USD", "USD/JPY", "AUD/USD", "USD/CHF"}
+	for i, key := range keys {
+		// Write through every node, owner or not.
+		nodes[i%len(nodes)].Set(key, float64(i+1), 30*time.Second)
+	}
+	nodes[0].Set("USD/SGD", 1.34)
+	nodes[1].SetMany(map[string]float64{"EUR/GBP": 0.86, "USD/CAD": 1.37}, 30*time.Second)
+	flush(t, nodes)
+
+	for _, n := range nodes {
+		for i, key := range keys {
+			rate, ttl, ok := n.cache.GetWithExpiry(key)
+			if !ok || rate != float64(i+1) || ttl != 30*time.Second {
+				t.Errorf("%s: %s = %v, %v, %v; want %v, 30s, true", n.Self(), key, rate, ttl, ok, float64(i+1))
+			}
+		}
+		if _, ttl, _ := n.cache.GetWithExpiry("USD/SGD"); ttl != time.Minute {
+			t.Errorf("%s: USD/SGD TTL = %v, want the default 1m", n.Self(), ttl)
+		}
+		if rate, ttl, _ := n.cache.GetWithExpiry("USD/CAD"); rate != 1.37 || ttl != 30*time.Second {
+			t.Errorf("%s: USD/CAD = %v, %v; want 1.37 from SetMany, 30s", n.Self(), rate, ttl)
+		}
+	}
+
+	// Every copy expires at the same moment.
+	clock.Advance(30 * time.Second)
+	for _, n := range nodes {
+		if _, ok := n.Get("USD/THB"); ok {
+			t.Errorf("%s: USD/THB still cached after its expiry", n.Self())
+		}
+	}
+}
+
+func TestNodeReplicatesDeletes(t *testing.T) {
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	nodes := newTestCluster(t, 3, clock, nil)
+
+	nodes[0].Set("USD/THB", 36.5)
+	flush(t, nodes)
+	if !nodes[2].Delete("USD/THB") {
+		t.Fatal("Delete of a live rate = false, want true")
+	}
+	flush(t, nodes)
+	if nodes[1].Delete("USD/THB") {
+		t.Fatal("Delete of a deleted rate = true, want false")
+	}
+	for _, n := range nodes {
+		if _, ok := n.Get("USD/THB"); ok {
+			t.Errorf("%s: USD/THB still cached after Delete", n.Self())
+		}
+	}
+}
+
+func TestNodeDropsValueOverwrittenByExpiredWrite(t *testing.T) {
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	nodes := newTestCluster(t, 1, clock, nil)
+	n := nodes[0]
+	n.Set("USD/THB", 36.5)
+	var reasons []fxcache.EvictionReason
+	n.cache.OnEvicted(func(_ string, _ float64, reason fxcache.EvictionReason) { reasons = append(reasons, reason) })
+
+	// A write that expired in transit, e.g. after a long retry.
+	body := fmt.Sprintf(`{"from":"peer","ops":[{"op":"set","key":"USD/THB","value":36.6,"expires_at":%d}]}`,
+		clock.Now().Add(-time.Second).UnixMilli())
+	rec := httptest.NewRecorder()
+	n.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, replicatePath, strings.NewReader(body)))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", rec.Code)
+	}
+	if rate, ok := n.Get("USD/THB"); ok {
+		t.Errorf("USD/THB = %v after an expired write, want it gone", rate)
+	}
+	// Nobody deleted it, so it is reported as expired.
+	if len(reasons) != 1 || reasons[0] != fxcache.ReasonExpired {
+		t.Errorf("eviction reasons = %v, want [%v]", reasons, fxcache.ReasonExpired)
+	}
+}
+
+func TestNodeHandlerLimitsBody(t *testing.T) {
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	n := newTestCluster(t, 1, clock, nil)[0]
+
+	body := `{"from":"peer","ops":[{"op":"set","key":"` + strings.Repeat("x", maxBodyBytes) + `"}]}`
+	rec := httptest.NewRecorder()
+	n.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, replicatePath, strings.NewReader(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rec.Code)
+	}
+}
+
+func TestNodeConvergesOnLastWrite(t *testing.T) {
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	nodes := newTestCluster(t, 3, clock, nil)
+
+	// Concurrent writes to one key from every node end up the same
+	// everywhere, since the owner orders them.
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				n.Set("USD/THB", float64(i*100+j))
+			}
+		}()
+	}
+	wg.Wait()
+	flush(t, nodes)
+
+	want, ok := nodes[0].Get("USD/THB")
+	if !ok {
+		t.Fatal("USD/THB not cached")
+	}
+	for _, n := range nodes[1:] {
+		if got, _ := n.Get("USD/THB"); got != want {
+			t.Errorf("%s: USD/THB = %v, %s has %v", n.Self(), got, nodes[0].Self(), want)
+		}
+	}
+}
+
+func TestNodeRetriesUnreachablePeer(t *testing.T) {
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	var failures atomic.Int64
+	nodes := newTestCluster(t, 3, clock, func(error) { failures.Add(1) })
+
+	// Take down a node that does not own the key, so the owner keeps
+	// retrying it.
+	key := "USD/THB"
+	var down *testNode
+	for _, n := range nodes {
+		if n.Owner(key) != n.Self() {
+			down = n
+			break
+		}
+	}
+	down.down.Store(true)
+	for _, n := range nodes {
+		if n != down {
+			n.Set(key, 36.5)
+			break
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for failures.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("no replication errors reported for the unreachable node")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := down.Get(key); ok {
+		t.Fatalf("%s received %s while down", down.Self(), key)
+	}
+
+	down.down.Store(false)
+	flush(t, nodes)
+	if rate, ok := down.Get(key); !ok || rate != 36.5 {
+		t.Errorf("%s: %s = %v, %v after recovering; want 36.5, true", down.Self(), key, rate, ok)
+	}
+}
+
+func TestNodeCloseDropsUndeliveredOps(t *testing.T) {
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	var mu sync.Mutex
+	var errs []error
+	nodes := newTestCluster(t, 2, clock, func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	})
+	nodes[1].down.Store(true)
+	for _, key := range []string{"USD/THB", "EUR/USD", "GBP/USD", "USD/JPY"} {
+		nodes[0].Set(key, 1)
+	}
+	nodes[0].Close()
+	nodes[0].Set("AUD/USD", 0.66)
+
+	// Nothing is left to send, so Flush returns at once.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nodes[0].Flush(ctx); err != nil {
+		t.Fatalf("Flush after Close: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var dropped int
+	for _, err := range errs {
+		if strings.Contains(err.Error(), "dropping") {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		t.Errorf("errors = %v, want the dropped operations reported", errs)
+	}
+}
+
+func TestNodeSecret(t *testing.T) {
+	var got atomic.Value
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get(secretHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer peer.Close()
+
+	cache, err := fxcache.NewFXRateCache(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.StopJanitor()
+	self := "http://self.invalid"
+	n, err := NewNode(cache, Config{Self: self, Peers: []string{self, peer.URL}, Secret: "s3cret"})
+	if err != nil {
+		t.Fatalf("NewNode: %v", err)
+	}
+	defer n.Close()
+
+	// Whether USD/THB is forwarded or replicated, it goes to peer.
+	n.Set("USD/THB", 36.5)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got.Load() != "s3cret" {
+		t.Errorf("peer got secret %q, want s3cret", got.Load())
+	}
+
+	body := `{"from":"peer","ops":[{"op":"del","key":"USD/THB"}]}`
+	for secret, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "s3cret": http.StatusNoContent} {
+		req := httptest.NewRequest(http.MethodPost, replicatePath, strings.NewReader(body))
+		if secret != "" {
+			req.Header.Set(secretHeader, secret)
+		}
+		rec := httptest.NewRecorder()
+		n.Handler().ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("request with secret %q: status %d, want %d", secret, rec.Code, want)
+		}
+	}
+}
+
+func TestNewNodeValidatesConfig(t *testing.T) {
+	cache, err := fxcache.NewFXRateCache(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.StopJanitor()
+	for _, cfg := range []Config{
+		{Self: "http://a", Peers: []string{"http://b"}},
+		{Peers: []string{"http://a"}},
+		{Self: "http://a", Peers: []string{"http://a"}, QueueSize: -1},
+	} {
+		if _, err := NewNode(cache, cfg); err == nil {
+			t.Errorf("NewNode(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+)
+
+// DefaultVirtualNodes is the number of points each node gets on the ring when
+// Config.VirtualNodes is zero. More points spread keys more evenly.
+const DefaultVirtualNodes = 64
+
+// Ring assigns keys to nodes by consistent hashing: every node is placed at
+// several points on a hash ring and a key belongs to the first node point at
+// or after the key's hash. Adding or removing a node only moves the keys next
+// to its points. A Ring is immutable.
+//
+// Hashes are FNV-1a, which unlike hash/maphash is the same in every process,
+// so all nodes agree on the owners.
+type Ring struct {
+	points []ringPoint
+	nodes  []string
+}
+
+type ringPoint struct {
+	hash uint64
+	node string
+}
+
+// NewRing returns a ring of nodes with vnodes points per node, or
+// DefaultVirtualNodes if vnodes is zero or negative.
+func NewRing(vnodes int, nodes ...string) *Ring {
+	if vnodes <= 0 {
+		vnodes = DefaultVirtualNodes
+	}
+	r := &Ring{nodes: slices.Clone(nodes)}
+	slices.Sort(r.nodes)
+	r.nodes = slices.Compact(r.nodes)
+	for _, node := range r.nodes {
+		for i := range vnodes {
+			r.points = append(r.points, ringPoint{hash: hash(node + "#" + strconv.Itoa(i)), node: node})
+		}
+	}
+	slices.SortFunc(r.points, func(a, b ringPoint) int {
+		if a.hash != b.hash {
+			if a.hash < b.hash {
+				return -1
+			}
+			return 1
+		}
+		// Break the (unlikely) tie the same way on every node.
+		if a.node < b.node {
+			return -1
+		}
+		return 1
+	})
+	return r
+}
+
+// Nodes returns the nodes of the ring, sorted.
+func (r *Ring) Nodes() []string {
+	return slices.Clone(r.nodes)
+}
+
+// Owner returns the node that owns key, or "" if the ring is empty.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i, _ := slices.BinarySearchFunc(r.points, h, func(p ringPoint, h uint64) int {
+		switch {
+		case p.hash < h:
+			return -1
+		case p.hash > h:
+			return 1
+		}
+		return 0
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].node
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
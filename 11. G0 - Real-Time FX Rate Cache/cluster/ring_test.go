@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRingOwner(t *testing.T) {
+	nodes := []string{"http://a", "http://b", "http://c"}
+	ring := NewRing(0, nodes...)
+	if got := len(ring.Nodes()); got != 3 {
+		t.Fatalf("Nodes() has %d nodes, want 3", got)
+	}
+
+	// The owner does not depend on the order the nodes are given in.
+	reordered := NewRing(0, "http://c", "http://a", "http://b", "http://a")
+	counts := map[string]int{}
+	for i := range 3000 {
+		key := fmt.Sprintf("PAIR%d", i)
+		owner := ring.Owner(key)
+		if other := reordered.Owner(key); other != owner {
+			t.Fatalf("Owner(%q) = %q and %q for the same nodes", key, owner, other)
+		}
+		counts[owner]++
+	}
+	for _, node := range nodes {
+		// Each node should get roughly a third of the keys.
+		if counts[node] < 600 || counts[node] > 1400 {
+			t.Errorf("%s owns %d of 3000 keys, want about 1000", node, counts[node])
+		}
+	}
+
+	if got := NewRing(0).Owner("USD/THB"); got != "" {
+		t.Errorf("Owner on an empty ring = %q, want \"\"", got)
+	}
+}
+
+func TestRingAddNodeMovesFewKeys(t *testing.T) {
+	before := NewRing(0, "http://a", "http://b", "http://c")
+	after := NewRing(0, "http://a", "http://b", "http://c", "http://d")
+	moved := 0
+	for i := range 3000 {
+		key := fmt.Sprintf("PAIR%d", i)
+		if o := after.Owner(key); o != before.Owner(key) {
+			if o != "http://d" {
+				t.Fatalf("Owner(%q) moved to %q, not to the new node", key, o)
+			}
+			moved++
+		}
+	}
+	// About a quarter of the keys should move to the new node.
+	if moved < 400 || moved > 1200 {
+		t.Errorf("%d of 3000 keys moved, want about 750", moved)
+	}
+}
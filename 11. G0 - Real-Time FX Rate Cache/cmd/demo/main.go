@@ -1,8 +1,13 @@
+// Command demo walks through the features of the fxcache package.
 package main
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
 )
 
 // --- Main function with demo runs ---
@@ -18,12 +23,16 @@ func main() {
 	runDemo4()
 	fmt.Println("\n========= DEMO 5: Multiple Keys & TTLs ========")
 	runDemo5()
+	fmt.Println("\n========= DEMO 6: Struct Values ========")
+	runDemo6()
+	fmt.Println("\n========= DEMO 7: Read-Through Loader ========")
+	runDemo7()
 }
 
 // runDemo1: Basic TTL functionality.
 func runDemo1() {
 	fmt.Println("Starting...")
-	cache, _ := NewTTLCache(200 * time.Millisecond)
+	cache, _ := fxcache.NewFXRateCache(200 * time.Millisecond)
 	defer cache.StopJanitor()
 
 	cache.Set("USD/THB", 36.5)
@@ -43,7 +52,7 @@ func runDemo1() {
 // runDemo2: Custom TTL functionality.
 func runDemo2() {
 	fmt.Println("Starting...")
-	cache, _ := NewTTLCache(500 * time.Millisecond)
+	cache, _ := fxcache.NewFXRateCache(500 * time.Millisecond)
 	defer cache.StopJanitor()
 
 	cache.Set("EUR/USD", 1.08, 100*time.Millisecond) // Custom 100ms TTL
@@ -62,7 +71,7 @@ func runDemo2() {
 // runDemo3: Updating a key's value and TTL.
 func runDemo3() {
 	fmt.Println("Starting...")
-	cache, _ := NewTTLCache(300 * time.Millisecond)
+	cache, _ := fxcache.NewFXRateCache(300 * time.Millisecond)
 	defer cache.StopJanitor()
 
 	cache.Set("JPY/THB", 0.23) // Expires in 300ms
@@ -86,7 +95,7 @@ func runDemo3() {
 // runDemo4: Getting a non-existent key.
 func runDemo4() {
 	fmt.Println("Starting...")
-	cache, _ := NewTTLCache(1 * time.Second)
+	cache, _ := fxcache.NewFXRateCache(1 * time.Second)
 	defer cache.StopJanitor()
 
 	if _, ok := cache.Get("GBP/USD"); !ok {
@@ -102,7 +111,7 @@ func runDemo4() {
 // runDemo5: Caching multiple keys with different TTLs.
 func runDemo5() {
 	fmt.Println("Starting...")
-	cache, _ := NewTTLCache(1 * time.Second)
+	cache, _ := fxcache.NewFXRateCache(1 * time.Second)
 	defer cache.StopJanitor()
 
 	cache.Set("AUD/USD", 0.66, 50*time.Millisecond)
@@ -117,3 +126,53 @@ func runDemo5() {
 	}
 	fmt.Println("Finished.")
 }
+
+// Quote is a two-sided FX quote used to show that the cache is not limited to float64.
+type Quote struct {
+	Bid       float64
+	Ask       float64
+	Mid       float64
+	Provider  string
+	Timestamp time.Time
+}
+
+// runDemo6: Caching struct values with a generic TTLCache.
+func runDemo6() {
+	fmt.Println("Starting...")
+	cache, _ := fxcache.NewTTLCache[string, Quote](1 * time.Second)
+	defer cache.StopJanitor()
+
+	cache.Set("USD/THB", Quote{Bid: 36.48, Ask: 36.52, Mid: 36.50, Provider: "demo", Timestamp: time.Now()})
+	if q, ok := cache.Get("USD/THB"); ok {
+		fmt.Printf("USD/THB quote: bid %.2f / ask %.2f from %s (OK)\n", q.Bid, q.Ask, q.Provider)
+	}
+	fmt.Println("Finished.")
+}
+
+// runDemo7: Loading a missing rate once for many concurrent callers.
+func runDemo7() {
+	fmt.Println("Starting...")
+	cache, _ := fxcache.NewFXRateCache(1 * time.Second)
+	defer cache.StopJanitor()
+
+	var calls int
+	fetchRate := func(ctx context.Context, pair string) (float64, error) {
+		calls++
+		time.Sleep(20 * time.Millisecond) // Simulate a provider round trip
+		return 36.5, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.GetOrLoad(context.Background(), "USD/THB", fetchRate)
+		}()
+	}
+	wg.Wait()
+
+	val, _ := cache.Get("USD/THB")
+	fmt.Printf("10 concurrent misses, provider called %d time(s), cached %.2f (OK)\n", calls, val)
+	fmt.Println("Finished.")
+}
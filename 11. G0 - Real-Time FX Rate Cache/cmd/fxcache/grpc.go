@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	fxcache "g0-real-time-fx-rate-cache"
+	fxcachev1 "g0-real-time-fx-rate-cache/api/fxcache/v1"
+)
+
+// rateService implements the gRPC RateService over a cache. Writes go
+// through writes, like the HTTP server's. Watch streams are fed from the
+// cache's OnSet and OnEvicted callbacks, so they also see replicated writes.
+type rateService struct {
+	fxcachev1.UnimplementedRateServiceServer
+
+	cache  *fxcache.FXRateCache
+	writes fxcache.RateWriter
+	clock  fxcache.Clock
+
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+	// done is closed by close to end every Watch stream on shutdown.
+	done chan struct{}
+}
+
+// newRateService returns a service for cache that writes through writes.
+// clock must be the cache's clock; it is used to report the remaining TTL of
+// watched rates.
+func newRateService(cache *fxcache.FXRateCache, writes fxcache.RateWriter, clock fxcache.Clock) *rateService {
+	s := &rateService{
+		cache:    cache,
+		writes:   writes,
+		clock:    clock,
+		watchers: make(map[*watcher]struct{}),
+		done:     make(chan struct{}),
+	}
+	cache.OnSet(func(pair string, rate float64, expiresAt time.Time) {
+		ttl := max(expiresAt.Sub(s.clock.Now()), 0)
+		s.publish(fxcachev1.WatchResponse_KIND_SET, pair, rate, ttl)
+	})
+	cache.OnEvicted(func(pair string, rate float64, reason fxcache.EvictionReason) {
+		switch reason {
+		case fxcache.ReasonExpired:
+			s.publish(fxcachev1.WatchResponse_KIND_EXPIRED, pair, rate, 0)
+		case fxcache.ReasonDeleted, fxcache.ReasonCapacity:
+			s.publish(fxcachev1.WatchResponse_KIND_DELETED, pair, rate, 0)
+		}
+		// A replaced rate is followed by the set of the new one.
+	})
+	return s
+}
+
+// close ends all Watch streams, so that the gRPC server can stop gracefully.
+func (s *rateService) close() {
+	close(s.done)
+}
+
+func (s *rateService) GetRate(ctx context.Context, req *fxcachev1.GetRateRequest) (*fxcachev1.GetRateResponse, error) {
+	pair, err := parsePair(req.GetPair())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	rate, ttl, ok := s.cache.GetWithExpiry(pair)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no rate for %s", pair)
+	}
+	return &fxcachev1.GetRateResponse{Rate: newRate(pair, rate, ttl)}, nil
+}
+
+func (s *rateService) SetRate(ctx context.Context, req *fxcachev1.SetRateRequest) (*fxcachev1.SetRateResponse, error) {
+	pair, err := parsePair(req.GetPair())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if rate := req.GetRate(); !(rate > 0) || math.IsInf(rate, 0) {
+		return nil, status.Error(codes.InvalidArgument, "rate must be a positive number")
+	}
+	if req.GetTtlMs() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl_ms must not be negative")
+	}
+
+	s.writes.Set(pair, req.GetRate(), time.Duration(req.GetTtlMs())*time.Millisecond)
+	rate, ttl, _ := s.cache.GetWithExpiry(pair)
+	return &fxcachev1.SetRateResponse{Rate: newRate(pair, rate, ttl)}, nil
+}
+
+func (s *rateService) DeleteRate(ctx context.Context, req *fxcachev1.DeleteRateRequest) (*fxcachev1.DeleteRateResponse, error) {
+	pair, err := parsePair(req.GetPair())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !s.writes.Delete(pair) {
+		return nil, status.Errorf(codes.NotFound, "no rate for %s", pair)
+	}
+	return &fxcachev1.DeleteRateResponse{}, nil
+}
+
+// Watch sends the current rates of the requested pairs and then every change
+// to them until the client goes away or the server shuts down.
+func (s *rateService) Watch(req *fxcachev1.WatchRequest, stream fxcachev1.RateService_WatchServer) error {
+	if len(req.GetPairs()) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one pair is required")
+	}
+	w := newWatcher()
+	for _, raw := range req.GetPairs() {
+		pair, err := parsePair(raw)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		w.pairs[pair] = true
+	}
+
+	// Register before reading the current rates, so that no change falls in
+	// between. A change that races with the read is conflated with it.
+	s.mu.Lock()
+	s.watchers[w] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers, w)
+		s.mu.Unlock()
+	}()
+	for pair := range w.pairs {
+		if rate, ttl, ok := s.cache.GetWithExpiry(pair); ok {
+			w.notify(&fxcachev1.WatchResponse{Kind: fxcachev1.WatchResponse_KIND_SET, Rate: newRate(pair, rate, ttl)})
+		}
+	}
+
+	for {
+		select {
+		case <-w.ready:
+			for _, event := range w.drain() {
+				if err := stream.Send(event); err != nil {
+					return err
+				}
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+// publish hands an event to every watcher of pair. It never blocks, so a slow
+// subscriber cannot hold up writers or the janitor.
+func (s *rateService) publish(kind fxcachev1.WatchResponse_Kind, pair string, rate float64, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for w := range s.watchers {
+		if w.pairs[pair] {
+			w.notify(&fxcachev1.WatchResponse{Kind: kind, Rate: newRate(pair, rate, ttl)})
+		}
+	}
+}
+
+func newRate(pair string, rate float64, ttl time.Duration) *fxcachev1.Rate {
+	return &fxcachev1.Rate{Pair: pair, Rate: rate, TtlMs: ttl.Milliseconds()}
+}
+
+// watcher buffers the events of one Watch stream. It keeps only the latest
+// event per pair, so its memory is bounded by the number of watched pairs: a
+// subscriber that sends slower than rates change skips intermediate updates
+// instead of falling further and further behind.
+type watcher struct {
+	pairs map[string]bool
+	// ready has room for one signal that events are pending.
+	ready chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]*fxcachev1.WatchResponse
+	// order lists the pending pairs by first arrival, so every pair gets its
+	// turn even while one of them changes constantly.
+	order []string
+}
+
+func newWatcher() *watcher {
+	return &watcher{
+		pairs:   make(map[string]bool),
+		ready:   make(chan struct{}, 1),
+		pending: make(map[string]*fxcachev1.WatchResponse),
+	}
+}
+
+// notify records event as the latest for its pair and wakes the stream.
+func (w *watcher) notify(event *fxcachev1.WatchResponse) {
+	pair := event.GetRate().GetPair()
+	w.mu.Lock()
+	if _, ok := w.pending[pair]; !ok {
+		w.order = append(w.order, pair)
+	}
+	w.pending[pair] = event
+	w.mu.Unlock()
+
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+// drain returns the pending events and clears them.
+func (w *watcher) drain() []*fxcachev1.WatchResponse {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events := make([]*fxcachev1.WatchResponse, len(w.order))
+	for i, pair := range w.order {
+		events[i] = w.pending[pair]
+	}
+	clear(w.pending)
+	w.order = w.order[:0]
+	return events
+}
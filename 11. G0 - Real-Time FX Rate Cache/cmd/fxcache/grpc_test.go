@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	fxcache "g0-real-time-fx-rate-cache"
+	fxcachev1 "g0-real-time-fx-rate-cache/api/fxcache/v1"
+)
+
+// newTestGRPC serves a rateService on a loopback port and returns a client.
+func newTestGRPC(t *testing.T) (fxcachev1.RateServiceClient, *fxcache.FXRateCache, *fxcache.ManualClock) {
+	t.Helper()
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	cache, err := fxcache.NewTTLCacheWithConfig[string, float64](fxcache.Config{
+		DefaultTTL:      time.Minute,
+		JanitorInterval: time.Second,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newRateService(cache, cache, clock)
+	srv := grpc.NewServer()
+	fxcachev1.RegisterRateServiceServer(srv, svc)
+	go srv.Serve(ln)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		svc.close()
+		srv.GracefulStop()
+		cache.StopJanitor()
+	})
+	return fxcachev1.NewRateServiceClient(conn), cache, clock
+}
+
+func TestGRPCUnary(t *testing.T) {
+	client, _, clock := newTestGRPC(t)
+	ctx := context.Background()
+
+	_, err := client.GetRate(ctx, &fxcachev1.GetRateRequest{Pair: "USD/THB"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("GetRate before SetRate: %v, want NotFound", err)
+	}
+
+	set, err := client.SetRate(ctx, &fxcachev1.SetRateRequest{Pair: "usd/thb", Rate: 36.5, TtlMs: 30_000})
+	if err != nil {
+		t.Fatalf("SetRate: %v", err)
+	}
+	if r := set.GetRate(); r.GetPair() != "USD/THB" || r.GetRate() != 36.5 || r.GetTtlMs() != 30_000 {
+		t.Fatalf("SetRate = %v, want USD/THB 36.5 with 30000ms", r)
+	}
+
+	clock.Advance(10 * time.Second)
+	got, err := client.GetRate(ctx, &fxcachev1.GetRateRequest{Pair: "USD/THB"})
+	if err != nil {
+		t.Fatalf("GetRate: %v", err)
+	}
+	if r := got.GetRate(); r.GetRate() != 36.5 || r.GetTtlMs() != 20_000 {
+		t.Fatalf("GetRate = %v, want 36.5 with 20000ms", r)
+	}
+
+	if _, err := client.DeleteRate(ctx, &fxcachev1.DeleteRateRequest{Pair: "USD/THB"}); err != nil {
+		t.Fatalf("DeleteRate: %v", err)
+	}
+	_, err = client.DeleteRate(ctx, &fxcachev1.DeleteRateRequest{Pair: "USD/THB"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("second DeleteRate: %v, want NotFound", err)
+	}
+
+	_, err = client.SetRate(ctx, &fxcachev1.SetRateRequest{Pair: "USDTHB", Rate: 36.5})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("SetRate with a bad pair: %v, want InvalidArgument", err)
+	}
+	for _, rate := range []float64{0, math.Inf(1), math.NaN()} {
+		_, err = client.SetRate(ctx, &fxcachev1.SetRateRequest{Pair: "USD/THB", Rate: rate})
+		if status.Code(err) != codes.InvalidArgument || status.Convert(err).Message() != "rate must be a positive number" {
+			t.Fatalf("SetRate with rate %v: %v, want InvalidArgument", rate, err)
+		}
+	}
+}
+
+func TestGRPCWatch(t *testing.T) {
+	client, cache, clock := newTestGRPC(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache.Set("USD/THB", 36.5, 2*time.Second)
+	stream, err := client.Watch(ctx, &fxcachev1.WatchRequest{Pairs: []string{"USD/THB", "EUR/USD"}})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	recv := func() *fxcachev1.WatchResponse {
+		t.Helper()
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		return event
+	}
+
+	// The stream starts with the current rate, which also shows that the
+	// watcher is registered.
+	if e := recv(); e.GetKind() != fxcachev1.WatchResponse_KIND_SET || e.GetRate().GetRate() != 36.5 || e.GetRate().GetTtlMs() != 2000 {
+		t.Fatalf("first event = %v, want current USD/THB", e)
+	}
+
+	cache.Set("GBP/USD", 1.27) // not watched
+	cache.Set("EUR/USD", 1.08)
+	if e := recv(); e.GetKind() != fxcachev1.WatchResponse_KIND_SET || e.GetRate().GetPair() != "EUR/USD" {
+		t.Fatalf("event = %v, want EUR/USD set", e)
+	}
+
+	clock.Advance(2 * time.Second)
+	if e := recv(); e.GetKind() != fxcachev1.WatchResponse_KIND_EXPIRED || e.GetRate().GetPair() != "USD/THB" || e.GetRate().GetRate() != 36.5 {
+		t.Fatalf("event = %v, want USD/THB expired", e)
+	}
+
+	cache.Delete("EUR/USD")
+	if e := recv(); e.GetKind() != fxcachev1.WatchResponse_KIND_DELETED || e.GetRate().GetPair() != "EUR/USD" {
+		t.Fatalf("event = %v, want EUR/USD deleted", e)
+	}
+}
+
+func TestWatcherConflatesSlowSubscriber(t *testing.T) {
+	w := newWatcher()
+	event := func(pair string, rate float64) *fxcachev1.WatchResponse {
+		return &fxcachev1.WatchResponse{Kind: fxcachev1.WatchResponse_KIND_SET, Rate: newRate(pair, rate, time.Minute)}
+	}
+
+	// Nobody drains while the rates change; notify must never block.
+	for i := range 1000 {
+		w.notify(event("USD/THB", 36+float64(i)/1000))
+	}
+	w.notify(event("EUR/USD", 1.08))
+	w.notify(event("USD/THB", 37))
+
+	<-w.ready
+	got := w.drain()
+	if len(got) != 2 {
+		t.Fatalf("drained %d events, want the latest of each of 2 pairs", len(got))
+	}
+	if got[0].GetRate().GetPair() != "USD/THB" || got[0].GetRate().GetRate() != 37 {
+		t.Fatalf("first event = %v, want the latest USD/THB", got[0])
+	}
+	if got[1].GetRate().GetPair() != "EUR/USD" {
+		t.Fatalf("second event = %v, want EUR/USD", got[1])
+	}
+	if more := w.drain(); len(more) != 0 {
+		t.Fatalf("second drain = %v, want nothing", more)
+	}
+}
+
+func TestRunEndsWatchOnShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan listenAddrs, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, []string{"-addr", "127.0.0.1:0", "-grpc-addr", "127.0.0.1:0", "-resp-addr", ""}, ready)
+	}()
+	addr := <-ready
+
+	conn, err := grpc.NewClient(addr.grpc, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := fxcachev1.NewRateServiceClient(conn)
+	if _, err := client.SetRate(context.Background(), &fxcachev1.SetRateRequest{Pair: "USD/THB", Rate: 36.5}); err != nil {
+		t.Fatalf("SetRate: %v", err)
+	}
+	stream, err := client.Watch(context.Background(), &fxcachev1.WatchRequest{Pairs: []string{"USD/THB"}})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Recv after shutdown: %v, want Unavailable", err)
+	}
+}
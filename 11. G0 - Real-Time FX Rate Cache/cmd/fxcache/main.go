@@ -0,0 +1,291 @@
+// Command fxcache serves an FX rate cache over HTTP/JSON and gRPC.
+//
+// HTTP routes:
+//
+//	GET    /rates/{pair}         rate and remaining TTL, 404 on a miss
+//	PUT    /rates/{pair}         {"rate": 36.5, "ttl_ms": 30000}; ttl_ms is optional
+//	DELETE /rates/{pair}         204, or 404 if there was no live rate
+//	GET    /rates?pairs=A/B,C/D  several rates at once
+//	GET    /healthz              liveness
+//	GET    /metrics              Prometheus metrics
+//
+// The gRPC RateService (api/fxcache/v1) offers GetRate, SetRate, DeleteRate
+// and a server-streaming Watch of rate changes. A Redis protocol listener
+// (package resp) lets redis-cli and Redis clients use the same cache.
+//
+// With -provider-url or -provider-csv, the -pairs are polled from an upstream
+// HTTP service or a CSV file every -poll-interval (package provider).
+//
+// With -cluster-self and -cluster-peers, the node joins a cluster (package
+// cluster): writes made over HTTP, gRPC and RESP and by the poller are
+// replicated to the -cluster-peers, which are received on
+// POST /cluster/v1/ops of the HTTP listener. The nodes must share
+// -cluster-secret, and the HTTP listener should be on a private network.
+//
+// On SIGINT or SIGTERM the poller stops, the servers stop accepting
+// requests, let in-flight ones finish, queued replication is flushed and then
+// the cache is closed.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	fxcache "g0-real-time-fx-rate-cache"
+	fxcachev1 "g0-real-time-fx-rate-cache/api/fxcache/v1"
+	"g0-real-time-fx-rate-cache/cluster"
+	"g0-real-time-fx-rate-cache/provider"
+	"g0-real-time-fx-rate-cache/resp"
+)
+
+// shutdownTimeout bounds how long in-flight requests may take after a signal.
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], nil); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// listenAddrs are the addresses the servers listen on.
+type listenAddrs struct {
+	http, grpc, resp string
+}
+
+// run parses args, serves until ctx is done and shuts down gracefully. If
+// ready is not nil, the listening addresses are sent on it once the servers
+// accept connections.
+func run(ctx context.Context, args []string, ready chan<- listenAddrs) error {
+	flags := flag.NewFlagSet("fxcache", flag.ContinueOnError)
+	addr := flags.String("addr", ":8080", "HTTP listen address")
+	grpcAddr := flags.String("grpc-addr", ":9090", "gRPC listen address, empty to disable gRPC")
+	respAddr := flags.String("resp-addr", ":6380", "Redis protocol (RESP) listen address, empty to disable it")
+	ttl := flags.Duration("ttl", fxcache.DefaultCacheTTL, "default rate TTL")
+	janitor := flags.Duration("janitor-interval", fxcache.DefaultJanitorInterval, "minimum time between expiry sweeps")
+	maxEntries := flags.Int("max-entries", 0, "maximum number of cached rates, 0 for unbounded")
+	snapshot := flags.String("snapshot", "", "snapshot file for warm restarts")
+	snapshotInterval := flags.Duration("snapshot-interval", time.Minute, "how often to write the snapshot; it is also written on shutdown")
+	providerURL := flags.String("provider-url", "", "base URL of an HTTP rate provider to poll")
+	providerCSV := flags.String("provider-csv", "", "CSV file of pair,rate rows to poll")
+	pairList := flags.String("pairs", "", "comma-separated pairs to poll, e.g. USD/THB,EUR/USD")
+	pollInterval := flags.Duration("poll-interval", 5*time.Second, "how often the provider is polled; rates live twice as long")
+	clusterSelf := flags.String("cluster-self", "", "base URL at which the peers reach this node's HTTP listener, e.g. http://10.0.0.1:8080; enables clustering")
+	clusterPeers := flags.String("cluster-peers", "", "comma-separated base URLs of every node of the cluster, including -cluster-self")
+	clusterSecret := flags.String("cluster-secret", "", "secret shared by the nodes of the cluster; required with -cluster-self")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *addr == "" {
+		return errors.New("-addr must not be empty")
+	}
+	var source provider.RateProvider
+	switch {
+	case *providerURL != "" && *providerCSV != "":
+		return errors.New("-provider-url and -provider-csv are mutually exclusive")
+	case *providerURL != "":
+		source = provider.NewHTTPProvider(*providerURL, *pollInterval)
+	case *providerCSV != "":
+		source = provider.NewCSVProvider(*providerCSV, *pollInterval)
+	}
+	var pairs []fxcache.CurrencyPair
+	if *pairList != "" {
+		for _, raw := range strings.Split(*pairList, ",") {
+			pair, err := fxcache.ParseCurrencyPair(raw)
+			if err != nil {
+				return fmt.Errorf("-pairs: %w", err)
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	if (source != nil) != (len(pairs) > 0) {
+		return errors.New("-pairs and a provider must be given together")
+	}
+	if *snapshot == "" {
+		*snapshotInterval = 0
+	}
+	if (*clusterSelf != "") != (*clusterPeers != "") {
+		return errors.New("-cluster-self and -cluster-peers must be given together")
+	}
+	if *clusterSelf != "" && *clusterSecret == "" {
+		return errors.New("-cluster-secret is required with -cluster-self")
+	}
+
+	clock := fxcache.SystemClock{}
+	cache, err := fxcache.NewTTLCacheWithConfig[string, float64](fxcache.Config{
+		Clock:            clock,
+		DefaultTTL:       *ttl,
+		JanitorInterval:  *janitor,
+		MaxEntries:       *maxEntries,
+		SnapshotPath:     *snapshot,
+		SnapshotInterval: *snapshotInterval,
+	})
+	if err != nil {
+		return fmt.Errorf("create cache: %w", err)
+	}
+	// Closes the cache on the early returns. After the shutdown below it
+	// returns at once, unless closing timed out there.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		cache.Close(ctx)
+	}()
+
+	// Everything that can fail is created before the first listener, so no
+	// error is returned with servers running.
+	var writes fxcache.RateWriter = cache
+	var node *cluster.Node
+	if *clusterSelf != "" {
+		node, err = cluster.NewNode(cache, cluster.Config{
+			Self:   *clusterSelf,
+			Peers:  strings.Split(*clusterPeers, ","),
+			Clock:  clock,
+			Secret: *clusterSecret,
+		})
+		if err != nil {
+			return fmt.Errorf("create cluster node: %w", err)
+		}
+		defer node.Close()
+		writes = node
+	}
+	var poller *provider.Poller
+	if source != nil {
+		poller, err = provider.NewPoller(cache, provider.Config{Provider: source, Pairs: pairs, Writer: writes})
+		if err != nil {
+			return fmt.Errorf("create poller: %w", err)
+		}
+	}
+
+	// Listen on every address before serving, so a bad address fails fast.
+	var listeners []net.Listener
+	listen := func(addr string) (net.Listener, error) {
+		if addr == "" {
+			return nil, nil
+		}
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+		return ln, nil
+	}
+	ln, err := listen(*addr)
+	if err != nil {
+		return err
+	}
+	gln, err := listen(*grpcAddr)
+	if err != nil {
+		return err
+	}
+	rln, err := listen(*respAddr)
+	if err != nil {
+		return err
+	}
+
+	errc := make(chan error, 3)
+	mux := newServer(cache, writes)
+	if node != nil {
+		mux.Handle("/cluster/", node.Handler())
+	}
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() { errc <- srv.Serve(ln) }()
+	log.Printf("fxcache listening on %s", ln.Addr())
+	if node != nil {
+		log.Printf("fxcache cluster node %s of %s", node.Self(), *clusterPeers)
+	}
+	addrs := listenAddrs{http: ln.Addr().String()}
+
+	var grpcSrv *grpc.Server
+	var svc *rateService
+	if gln != nil {
+		svc = newRateService(cache, writes, clock)
+		grpcSrv = grpc.NewServer()
+		fxcachev1.RegisterRateServiceServer(grpcSrv, svc)
+		go func() { errc <- grpcSrv.Serve(gln) }()
+		log.Printf("fxcache gRPC listening on %s", gln.Addr())
+		addrs.grpc = gln.Addr().String()
+	}
+
+	var respSrv *resp.Server
+	if rln != nil {
+		respSrv = resp.NewServer(cache)
+		respSrv.Writer = writes
+		go func() { errc <- respSrv.Serve(rln) }()
+		log.Printf("fxcache RESP listening on %s", rln.Addr())
+		addrs.resp = rln.Addr().String()
+	}
+	var polling sync.WaitGroup
+	pollCtx, stopPolling := context.WithCancel(ctx)
+	defer stopPolling()
+	if poller != nil {
+		polling.Add(1)
+		go func() {
+			defer polling.Done()
+			poller.Run(pollCtx)
+		}()
+		log.Printf("fxcache polling %d pairs from %s every %v", len(pairs), source.Name(), *pollInterval)
+	}
+	if ready != nil {
+		ready <- addrs
+	}
+
+	var serveErr error
+	select {
+	case serveErr = <-errc:
+	case <-ctx.Done():
+	}
+
+	log.Print("fxcache shutting down")
+	stopPolling()
+	polling.Wait()
+	if respSrv != nil {
+		respSrv.Close()
+	}
+	if grpcSrv != nil {
+		// Watch streams never end on their own; close them first so that
+		// GracefulStop only waits for unary calls.
+		svc.close()
+		grpcSrv.GracefulStop()
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if node != nil {
+		// Undelivered writes are reported by Close; the peers keep serving
+		// their own copies either way.
+		if err := node.Flush(shutdownCtx); err != nil {
+			log.Printf("fxcache: flush cluster: %v", err)
+		}
+		node.Close()
+	}
+	if err := cache.Close(shutdownCtx); err != nil {
+		return fmt.Errorf("close cache: %w", err)
+	}
+	if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) && !errors.Is(serveErr, resp.ErrServerClosed) {
+		return serveErr
+	}
+	return nil
+}
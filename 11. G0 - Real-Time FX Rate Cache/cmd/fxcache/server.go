@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+)
+
+// rateResponse is the JSON form of one cached rate.
+type rateResponse struct {
+	Pair string  `json:"pair"`
+	Rate float64 `json:"rate"`
+	// TTLMillis is the remaining lifetime of the rate in milliseconds.
+	TTLMillis int64 `json:"ttl_ms"`
+}
+
+// bulkResponse answers GET /rates?pairs=...
+type bulkResponse struct {
+	Rates   []rateResponse `json:"rates"`
+	Missing []string       `json:"missing"`
+}
+
+// putRequest is the body of PUT /rates/{pair}. TTLMillis is optional; zero
+// means the cache's default TTL.
+type putRequest struct {
+	Rate      float64 `json:"rate"`
+	TTLMillis int64   `json:"ttl_ms"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// server serves the rates held in a cache over HTTP/JSON. Reads come from
+// cache; writes go through writes, which is the cache itself or a
+// cluster.Node replicating it.
+type server struct {
+	cache  *fxcache.FXRateCache
+	writes fxcache.RateWriter
+}
+
+// newServer returns the HTTP handler for cache, writing through writes. Pairs
+// are written as BASE/QUOTE, so /rates/USD/THB and /rates/usd%2Fthb name the
+// same rate.
+func newServer(cache *fxcache.FXRateCache, writes fxcache.RateWriter) *http.ServeMux {
+	s := &server{cache: cache, writes: writes}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rates/{pair...}", s.getRate)
+	mux.HandleFunc("PUT /rates/{pair...}", s.putRate)
+	mux.HandleFunc("DELETE /rates/{pair...}", s.deleteRate)
+	mux.HandleFunc("GET /rates", s.getRates)
+	mux.HandleFunc("GET /healthz", s.health)
+	mux.Handle("GET /metrics", fxcache.NewMetricsHandler(cache))
+	return mux
+}
+
+func (s *server) getRate(w http.ResponseWriter, r *http.Request) {
+	pair, err := parsePair(r.PathValue("pair"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	rate, ttl, ok := s.cache.GetWithExpiry(pair)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no rate for %s", pair))
+		return
+	}
+	writeJSON(w, http.StatusOK, rateResponse{Pair: pair, Rate: rate, TTLMillis: ttl.Milliseconds()})
+}
+
+func (s *server) putRate(w http.ResponseWriter, r *http.Request) {
+	pair, err := parsePair(r.PathValue("pair"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var req putRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	if !(req.Rate > 0) || math.IsInf(req.Rate, 0) {
+		writeError(w, http.StatusBadRequest, errors.New("rate must be a positive number"))
+		return
+	}
+	if req.TTLMillis < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("ttl_ms must not be negative"))
+		return
+	}
+
+	s.writes.Set(pair, req.Rate, time.Duration(req.TTLMillis)*time.Millisecond)
+	rate, ttl, _ := s.cache.GetWithExpiry(pair)
+	writeJSON(w, http.StatusOK, rateResponse{Pair: pair, Rate: rate, TTLMillis: ttl.Milliseconds()})
+}
+
+func (s *server) deleteRate(w http.ResponseWriter, r *http.Request) {
+	pair, err := parsePair(r.PathValue("pair"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !s.writes.Delete(pair) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no rate for %s", pair))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getRates looks up a comma-separated list of pairs. Pairs without a live
+// rate are listed under "missing" rather than failing the request.
+func (s *server) getRates(w http.ResponseWriter, r *http.Request) {
+	param := r.URL.Query().Get("pairs")
+	if param == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing pairs query parameter"))
+		return
+	}
+
+	var pairs []string
+	for _, raw := range strings.Split(param, ",") {
+		pair, err := parsePair(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		pairs = append(pairs, pair)
+	}
+
+	// One lookup under a single lock, so the rates are a consistent snapshot.
+	rates, ttls := s.cache.GetManyWithExpiry(pairs)
+	resp := bulkResponse{Rates: []rateResponse{}, Missing: []string{}}
+	for _, pair := range pairs {
+		if rate, ok := rates[pair]; ok {
+			resp.Rates = append(resp.Rates, rateResponse{Pair: pair, Rate: rate, TTLMillis: ttls[pair].Milliseconds()})
+		} else {
+			resp.Missing = append(resp.Missing, pair)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "entries": s.cache.Len()})
+}
+
+// parsePair normalizes a currency pair such as "usd/thb" to "USD/THB". Both
+// currencies must be ISO 4217 codes.
+func parsePair(raw string) (string, error) {
+	pair, err := fxcache.ParseCurrencyPair(raw)
+	if err != nil {
+		return "", err
+	}
+	return pair.String(), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+	"g0-real-time-fx-rate-cache/provider/providertest"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *fxcache.ManualClock) {
+	t.Helper()
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	cache, err := fxcache.NewTTLCacheWithConfig[string, float64](fxcache.Config{
+		DefaultTTL:      time.Minute,
+		JanitorInterval: time.Minute,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	srv := httptest.NewServer(newServer(cache, cache))
+	t.Cleanup(func() {
+		srv.Close()
+		cache.StopJanitor()
+	})
+	return srv, clock
+}
+
+// do sends a request and decodes a JSON response into out, if given.
+func do(t *testing.T, method, url, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestRateLifecycle(t *testing.T) {
+	srv, clock := newTestServer(t)
+
+	if code := do(t, "GET", srv.URL+"/rates/USD/THB", "", nil); code != http.StatusNotFound {
+		t.Fatalf("GET before PUT = %d, want 404", code)
+	}
+
+	var put rateResponse
+	if code := do(t, "PUT", srv.URL+"/rates/usd/thb", `{"rate": 36.5, "ttl_ms": 30000}`, &put); code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200", code)
+	}
+	if want := (rateResponse{Pair: "USD/THB", Rate: 36.5, TTLMillis: 30000}); put != want {
+		t.Fatalf("PUT response = %+v, want %+v", put, want)
+	}
+
+	clock.Advance(10 * time.Second)
+	var got rateResponse
+	if code := do(t, "GET", srv.URL+"/rates/USD%2FTHB", "", &got); code != http.StatusOK {
+		t.Fatalf("GET = %d, want 200", code)
+	}
+	if want := (rateResponse{Pair: "USD/THB", Rate: 36.5, TTLMillis: 20000}); got != want {
+		t.Fatalf("GET response = %+v, want %+v", got, want)
+	}
+
+	if code := do(t, "DELETE", srv.URL+"/rates/USD/THB", "", nil); code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d, want 204", code)
+	}
+	if code := do(t, "DELETE", srv.URL+"/rates/USD/THB", "", nil); code != http.StatusNotFound {
+		t.Fatalf("second DELETE = %d, want 404", code)
+	}
+}
+
+func TestRateExpires(t *testing.T) {
+	srv, clock := newTestServer(t)
+
+	// Without ttl_ms the cache's default TTL of one minute applies.
+	do(t, "PUT", srv.URL+"/rates/EUR/USD", `{"rate": 1.08}`, nil)
+	clock.Advance(time.Minute)
+	if code := do(t, "GET", srv.URL+"/rates/EUR/USD", "", nil); code != http.StatusNotFound {
+		t.Fatalf("GET after TTL = %d, want 404", code)
+	}
+}
+
+func TestBulkGet(t *testing.T) {
+	srv, _ := newTestServer(t)
+	do(t, "PUT", srv.URL+"/rates/USD/THB", `{"rate": 36.5}`, nil)
+	do(t, "PUT", srv.URL+"/rates/EUR/USD", `{"rate": 1.08}`, nil)
+
+	var got bulkResponse
+	if code := do(t, "GET", srv.URL+"/rates?pairs=USD/THB,eur/usd,GBP/USD", "", &got); code != http.StatusOK {
+		t.Fatalf("bulk GET = %d, want 200", code)
+	}
+	if len(got.Rates) != 2 || got.Rates[0].Pair != "USD/THB" || got.Rates[1].Pair != "EUR/USD" {
+		t.Fatalf("bulk rates = %+v, want USD/THB and EUR/USD", got.Rates)
+	}
+	if len(got.Missing) != 1 || got.Missing[0] != "GBP/USD" {
+		t.Fatalf("bulk missing = %v, want [GBP/USD]", got.Missing)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	srv, _ := newTestServer(t)
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", "/rates/USDTHB", ""},
+		{"GET", "/rates/US/THB", ""},
+		{"GET", "/rates", ""},
+		{"GET", "/rates?pairs=USD/THB,nope", ""},
+		{"PUT", "/rates/USD/THB", `{"rate": -1}`},
+		{"PUT", "/rates/USD/THB", `{"rate": 36.5, "ttl_ms": -5}`},
+		{"PUT", "/rates/USD/THB", `{"price": 36.5}`},
+		{"PUT", "/rates/USD/THB", `not json`},
+	}
+	for _, tt := range tests {
+		var resp errorResponse
+		if code := do(t, tt.method, srv.URL+tt.path, tt.body, &resp); code != http.StatusBadRequest || resp.Error == "" {
+			t.Errorf("%s %s %s = %d %q, want 400 with an error", tt.method, tt.path, tt.body, code, resp.Error)
+		}
+	}
+}
+
+func TestHealthAndMetrics(t *testing.T) {
+	srv, _ := newTestServer(t)
+	do(t, "PUT", srv.URL+"/rates/USD/THB", `{"rate": 36.5}`, nil)
+
+	var health map[string]any
+	if code := do(t, "GET", srv.URL+"/healthz", "", &health); code != http.StatusOK || health["status"] != "ok" {
+		t.Fatalf("GET /healthz = %d %v, want 200 ok", code, health)
+	}
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "ttlcache_sets_total 1\n") {
+		t.Fatalf("metrics missing set count:\n%s", body)
+	}
+}
+
+func TestRunShutsDownGracefully(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "fx.snapshot")
+	args := []string{"-addr", "127.0.0.1:0", "-grpc-addr", "127.0.0.1:0", "-resp-addr", "127.0.0.1:0", "-snapshot", snapshot}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan listenAddrs, 1)
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, args, ready) }()
+
+	addr := <-ready
+	if code := do(t, "PUT", "http://"+addr.http+"/rates/USD/THB", `{"rate": 36.5}`, nil); code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200", code)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	// Stopping the janitor on shutdown wrote the final snapshot, so a restart
+	// is warm.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() { done <- run(ctx, args, ready) }()
+	addr = <-ready
+	var got rateResponse
+	if code := do(t, "GET", "http://"+addr.http+"/rates/USD/THB", "", &got); code != http.StatusOK || got.Rate != 36.5 {
+		t.Fatalf("GET after restart = %d %+v, want 200 with 36.5", code, got)
+	}
+
+	// The RESP listener serves the same cache.
+	conn, err := net.Dial("tcp", addr.resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET USD/THB\r\n")
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || reply != "$4\r\n" {
+		t.Fatalf("RESP GET reply = %q, %v, want a 4-byte bulk string", reply, err)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+}
+
+func TestRunPollsProvider(t *testing.T) {
+	upstream := providertest.NewServer(map[string]float64{"USD/THB": 36.5})
+	defer upstream.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan listenAddrs, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, []string{"-addr", "127.0.0.1:0", "-grpc-addr", "", "-resp-addr", "",
+			"-provider-url", upstream.URL, "-pairs", "usd/thb", "-poll-interval", "50ms"}, ready)
+	}()
+	addr := <-ready
+
+	// The first poll runs in the background right after startup.
+	var got rateResponse
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if do(t, "GET", "http://"+addr.http+"/rates/USD/THB", "", &got) == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("USD/THB was never polled from the provider")
+		}
+	}
+	if got.Rate != 36.5 || got.TTLMillis > 100 {
+		t.Fatalf("polled rate = %+v, want 36.5 living twice the poll interval", got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+}
+
+func TestRunRejectsBadProviderFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-pairs", "USD/THB"},
+		{"-provider-url", "http://localhost:1"},
+		{"-provider-url", "http://localhost:1", "-provider-csv", "rates.csv", "-pairs", "USD/THB"},
+		{"-provider-csv", "rates.csv", "-pairs", "USDTHB"},
+	} {
+		if err := run(context.Background(), args, nil); err == nil {
+			t.Errorf("run(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestRunFailsBeforeListening(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// A zero poll interval is only rejected by the poller.
+	args := []string{"-addr", addr, "-grpc-addr", "", "-resp-addr", "",
+		"-provider-csv", "rates.csv", "-pairs", "USD/THB", "-poll-interval", "0s"}
+	if err := run(context.Background(), args, nil); err == nil {
+		t.Fatal("run with a zero poll interval succeeded, want an error")
+	}
+	// The address is free again, so no server was left running.
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("address still in use after run failed: %v", err)
+	}
+	ln.Close()
+}
+
+func TestRunReplicatesWrites(t *testing.T) {
+	for _, args := range [][]string{
+		{"-cluster-self", "http://127.0.0.1:1", "-cluster-secret", "s"},
+		{"-cluster-self", "http://127.0.0.1:1", "-cluster-peers", "http://127.0.0.1:1"},
+		{"-cluster-self", "http://127.0.0.1:1", "-cluster-peers", "http://127.0.0.1:2", "-cluster-secret", "s"},
+	} {
+		if err := run(context.Background(), args, nil); err == nil {
+			t.Errorf("run(%q) succeeded, want an error", args)
+		}
+	}
+
+	// The peers must know each other's URLs before they start.
+	var addrs []string
+	for range 2 {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs = append(addrs, ln.Addr().String())
+		ln.Close()
+	}
+	peers := "http://" + addrs[0] + ",http://" + addrs[1]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan listenAddrs, 2)
+	done := make(chan error, 2)
+	for _, addr := range addrs {
+		go func() {
+			done <- run(ctx, []string{"-addr", addr, "-grpc-addr", "", "-resp-addr", "",
+				"-cluster-self", "http://" + addr, "-cluster-peers", peers, "-cluster-secret", "s3cret"}, ready)
+		}()
+	}
+	<-ready
+	<-ready
+
+	waitFor := func(addr string, want int) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+			if do(t, "GET", "http://"+addr+"/rates/USD/THB", "", nil) == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("GET USD/THB on %s never returned %d", addr, want)
+			}
+		}
+	}
+	if code := do(t, "PUT", "http://"+addrs[0]+"/rates/USD/THB", `{"rate": 36.5}`, nil); code != http.StatusOK {
+		t.Fatalf("PUT = %d, want 200", code)
+	}
+	waitFor(addrs[1], http.StatusOK)
+	if code := do(t, "DELETE", "http://"+addrs[1]+"/rates/USD/THB", "", nil); code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d, want 204", code)
+	}
+	waitFor(addrs[0], http.StatusNotFound)
+
+	// The replication route needs the secret.
+	if code := do(t, "POST", "http://"+addrs[0]+"/cluster/v1/ops", `{"ops": []}`, nil); code != http.StatusUnauthorized {
+		t.Fatalf("POST without the secret = %d, want 401", code)
+	}
+
+	cancel()
+	for range addrs {
+		if err := <-done; err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package fxcache
+
+import "testing"
+
+func TestParseCurrencyPair(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    CurrencyPair
+		wantErr bool
+	}{
+		{in: "USD/THB", want: CurrencyPair{"USD", "THB"}},
+		{in: " jpy/thb ", want: CurrencyPair{"JPY", "THB"}},
+		{in: "USDTHB", wantErr: true},
+		{in: "USD/XYZ", wantErr: true},
+		{in: "USD/USD", wantErr: true},
+		{in: "US/THB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCurrencyPair(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseCurrencyPair(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseCurrencyPair(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	pair := CurrencyPair{"USD", "THB"}
+	if pair.String() != "USD/THB" || pair.Inverse().String() != "THB/USD" {
+		t.Fatalf("String/Inverse = %s/%s, want USD/THB and THB/USD", pair, pair.Inverse())
+	}
+}
+
+func TestCurrencyMinorUnits(t *testing.T) {
+	for code, want := range map[Currency]int{"USD": 2, "JPY": 0, "KWD": 3, "CLF": 4, "XYZ": -1} {
+		if got := code.MinorUnits(); got != want {
+			t.Errorf("%s.MinorUnits() = %d, want %d", code, got, want)
+		}
+	}
+	if c, err := ParseCurrency("thb"); err != nil || c != "THB" {
+		t.Fatalf(`ParseCurrency("thb") = %q, %v, want THB`, c, err)
+	}
+}
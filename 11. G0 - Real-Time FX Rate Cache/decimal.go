@@ -0,0 +1,221 @@
+package fxcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact fixed-point decimal number, used for prices and
+// amounts so that conversions are not subject to binary float rounding. Its
+// value is coef × 10^-scale. The zero value is 0.
+//
+// Decimals are immutable; every operation returns a new value.
+type Decimal struct {
+	coef  *big.Int
+	scale int32
+}
+
+var bigTen = big.NewInt(10)
+
+// NewDecimal returns coef × 10^-scale, e.g. NewDecimal(36512, 3) is 36.512.
+func NewDecimal(coef int64, scale int32) Decimal {
+	return newDecimal(big.NewInt(coef), scale)
+}
+
+// newDecimal returns coef × 10^-scale, normalizing a negative scale so that
+// scale is never below zero. It takes ownership of coef.
+func newDecimal(coef *big.Int, scale int32) Decimal {
+	if scale < 0 {
+		coef.Mul(coef, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{coef: coef, scale: scale}
+}
+
+// ParseDecimal parses a decimal such as "1234.50" or "-0.0001". Exponents
+// are not accepted.
+func ParseDecimal(s string) (Decimal, error) {
+	digits, neg := s, false
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		digits, neg = digits[1:], digits[0] == '-'
+	}
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole+frac == "" || strings.Trim(whole+frac, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	coef, _ := new(big.Int).SetString(whole+frac, 10)
+	if neg {
+		coef.Neg(coef)
+	}
+	return Decimal{coef: coef, scale: int32(len(frac))}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on invalid input. It is
+// meant for constants.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// DecimalFromFloat returns f rounded to places decimal places. It returns an
+// error for NaN and infinities.
+func DecimalFromFloat(f float64, places int32) (Decimal, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, fmt.Errorf("invalid decimal %v", f)
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'f', int(max(places, 0)), 64))
+}
+
+// int returns the coefficient, treating the zero Decimal as 0.
+func (d Decimal) int() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// Scale returns the number of decimal places d is written with.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// Cmp compares d and e and returns -1, 0 or +1.
+func (d Decimal) Cmp(e Decimal) int {
+	a, b := align(d, e)
+	return a.Cmp(b)
+}
+
+// Add returns d + e.
+func (d Decimal) Add(e Decimal) Decimal {
+	a, b := align(d, e)
+	return Decimal{coef: new(big.Int).Add(a, b), scale: max(d.scale, e.scale)}
+}
+
+// Sub returns d - e.
+func (d Decimal) Sub(e Decimal) Decimal {
+	a, b := align(d, e)
+	return Decimal{coef: new(big.Int).Sub(a, b), scale: max(d.scale, e.scale)}
+}
+
+// Mul returns d × e exactly.
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.int(), e.int()), scale: d.scale + e.scale}
+}
+
+// Div returns d / e rounded to places decimal places, half away from zero.
+// It panics if e is zero.
+func (d Decimal) Div(e Decimal, places int32) Decimal {
+	if e.Sign() == 0 {
+		panic("fxcache: decimal division by zero")
+	}
+	// d/e = dc/ec × 10^(e.scale-d.scale), so the result coefficient is
+	// dc × 10^(places+e.scale-d.scale) / ec.
+	num, den := new(big.Int).Set(d.int()), new(big.Int).Set(e.int())
+	if exp := places + e.scale - d.scale; exp >= 0 {
+		num.Mul(num, pow10(exp))
+	} else {
+		den.Mul(den, pow10(-exp))
+	}
+	return newDecimal(quoRound(num, den), places)
+}
+
+// Round returns d rounded to places decimal places, half away from zero. The
+// result is always written with exactly places decimals, e.g. 100.5 rounded
+// to 2 places is 100.50.
+func (d Decimal) Round(places int32) Decimal {
+	if places >= d.scale {
+		return newDecimal(new(big.Int).Mul(d.int(), pow10(places-d.scale)), places)
+	}
+	return newDecimal(quoRound(new(big.Int).Set(d.int()), pow10(d.scale-places)), places)
+}
+
+// Shift returns d × 10^n.
+func (d Decimal) Shift(n int32) Decimal {
+	return newDecimal(new(big.Int).Set(d.int()), d.scale-n)
+}
+
+// Float64 returns the nearest float64 to d.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String returns d in plain notation with its scale, e.g. "36.510".
+func (d Decimal) String() string {
+	coef := d.int()
+	digits := new(big.Int).Abs(coef).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	}
+	if coef.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON encodes d as a JSON string, so no precision is lost.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts a JSON string or number.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// align returns the coefficients of d and e at their common scale.
+func align(d, e Decimal) (*big.Int, *big.Int) {
+	a, b := d.int(), e.int()
+	switch {
+	case d.scale < e.scale:
+		a = new(big.Int).Mul(a, pow10(e.scale-d.scale))
+	case d.scale > e.scale:
+		b = new(big.Int).Mul(b, pow10(d.scale-e.scale))
+	}
+	return a, b
+}
+
+// quoRound returns num / den rounded half away from zero.
+func quoRound(num, den *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	// Round away from zero when |2r| >= |den|.
+	if new(big.Int).Abs(r.Lsh(r, 1)).Cmp(new(big.Int).Abs(den)) >= 0 {
+		if num.Sign()*den.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,63 @@
+package fxcache
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecimalArithmetic(t *testing.T) {
+	d := MustParseDecimal
+	tests := []struct {
+		name string
+		got  Decimal
+		want string
+	}{
+		{"add", d("0.1").Add(d("0.2")), "0.3"},
+		{"sub", d("1").Sub(d("1.005")), "-0.005"},
+		{"mul", d("1000.50").Mul(d("36.512")), "36530.25600"},
+		{"div", d("1").Div(d("3"), 4), "0.3333"},
+		{"div rounds up", d("2").Div(d("3"), 4), "0.6667"},
+		{"div negative", d("-2").Div(d("3"), 2), "-0.67"},
+		{"round half up", d("2.345").Round(2), "2.35"},
+		{"round half negative", d("-2.345").Round(2), "-2.35"},
+		{"round down", d("2.3449").Round(2), "2.34"},
+		{"round pads", d("100.5").Round(2), "100.50"},
+		{"round to tens", d("1234.5").Round(-1), "1230"},
+		{"shift", d("0.00012").Shift(4), "1.2"},
+		{"zero value", Decimal{}.Add(d("1.5")), "1.5"},
+		{"small", d("-0.0001"), "-0.0001"},
+	}
+	for _, tt := range tests {
+		if tt.got.String() != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if d("1.50").Cmp(d("1.5")) != 0 || d("-1").Cmp(Decimal{}) >= 0 {
+		t.Fatal("Cmp does not compare values")
+	}
+	for _, bad := range []string{"", ".", "1e5", "1.2.3", "--1", "abc"} {
+		if _, err := ParseDecimal(bad); err == nil {
+			t.Errorf("ParseDecimal(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestDecimalFromFloatAndJSON(t *testing.T) {
+	f, err := DecimalFromFloat(36.51249, 4)
+	if err != nil || f.String() != "36.5125" {
+		t.Fatalf("DecimalFromFloat = %s, %v, want 36.5125", f, err)
+	}
+
+	data, err := json.Marshal(MustParseDecimal("1.08012"))
+	if err != nil || string(data) != `"1.08012"` {
+		t.Fatalf("Marshal = %s, %v, want a string", data, err)
+	}
+	var back Decimal
+	if err := json.Unmarshal(data, &back); err != nil || back.String() != "1.08012" {
+		t.Fatalf("Unmarshal = %s, %v, want 1.08012", back, err)
+	}
+	if err := json.Unmarshal([]byte("2.5"), &back); err != nil || back.String() != "2.5" {
+		t.Fatalf("Unmarshal of a number = %s, %v, want 2.5", back, err)
+	}
+}
@@ -0,0 +1,231 @@
+package fxcache
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"reflect"
+)
+
+// EvictionPolicy selects which entry is removed when a capacity-bounded cache
+// is full.
+type EvictionPolicy int
+
+const (
+	// EvictLRU removes the least recently used entry. It is the default.
+	EvictLRU EvictionPolicy = iota
+	// EvictLFU removes the least frequently used entry, breaking ties by age.
+	EvictLFU
+	// EvictFIFO removes the entry that was inserted first.
+	EvictFIFO
+)
+
+// String returns the policy name.
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictLRU:
+		return "LRU"
+	case EvictLFU:
+		return "LFU"
+	case EvictFIFO:
+		return "FIFO"
+	default:
+		return fmt.Sprintf("EvictionPolicy(%d)", int(p))
+	}
+}
+
+// evictor tracks key usage for an eviction policy. It is not safe for
+// concurrent use; TTLCache guards it with evictMu.
+type evictor[K comparable] interface {
+	// add records a newly inserted key.
+	add(key K)
+	// touch records a read or an overwrite of an existing key.
+	touch(key K)
+	// remove forgets a key.
+	remove(key K)
+	// victim returns the next key to evict, never returning exclude.
+	victim(exclude K) (K, bool)
+}
+
+func newEvictor[K comparable](p EvictionPolicy) (evictor[K], error) {
+	switch p {
+	case EvictLRU:
+		return newListEvictor[K](true), nil
+	case EvictLFU:
+		return newLFUEvictor[K](), nil
+	case EvictFIFO:
+		return newListEvictor[K](false), nil
+	default:
+		return nil, fmt.Errorf("%w %v", ErrUnknownEvictionPolicy, p)
+	}
+}
+
+// listEvictor implements LRU and FIFO. The front of the list is the newest
+// entry and the back is the next victim.
+type listEvictor[K comparable] struct {
+	order        *list.List
+	elems        map[K]*list.Element
+	moveOnAccess bool
+}
+
+func newListEvictor[K comparable](moveOnAccess bool) *listEvictor[K] {
+	return &listEvictor[K]{
+		order:        list.New(),
+		elems:        make(map[K]*list.Element),
+		moveOnAccess: moveOnAccess,
+	}
+}
+
+func (l *listEvictor[K]) add(key K) {
+	l.elems[key] = l.order.PushFront(key)
+}
+
+func (l *listEvictor[K]) touch(key K) {
+	if !l.moveOnAccess {
+		return
+	}
+	if e, ok := l.elems[key]; ok {
+		l.order.MoveToFront(e)
+	}
+}
+
+func (l *listEvictor[K]) remove(key K) {
+	if e, ok := l.elems[key]; ok {
+		l.order.Remove(e)
+		delete(l.elems, key)
+	}
+}
+
+func (l *listEvictor[K]) victim(exclude K) (K, bool) {
+	for e := l.order.Back(); e != nil; e = e.Prev() {
+		if key := e.Value.(K); key != exclude {
+			return key, true
+		}
+	}
+	var zero K
+	return zero, false
+}
+
+// lfuEvictor keeps keys in a min-heap ordered by access count, then by the
+// time of the last access.
+type lfuEvictor[K comparable] struct {
+	items lfuHeap[K]
+	index map[K]*lfuItem[K]
+	seq   uint64
+}
+
+type lfuItem[K comparable] struct {
+	key   K
+	freq  uint64
+	seq   uint64
+	index int
+}
+
+func newLFUEvictor[K comparable]() *lfuEvictor[K] {
+	return &lfuEvictor[K]{index: make(map[K]*lfuItem[K])}
+}
+
+func (l *lfuEvictor[K]) add(key K) {
+	l.seq++
+	item := &lfuItem[K]{key: key, freq: 1, seq: l.seq}
+	l.index[key] = item
+	heap.Push(&l.items, item)
+}
+
+func (l *lfuEvictor[K]) touch(key K) {
+	if item, ok := l.index[key]; ok {
+		l.seq++
+		item.freq++
+		item.seq = l.seq
+		heap.Fix(&l.items, item.index)
+	}
+}
+
+func (l *lfuEvictor[K]) remove(key K) {
+	if item, ok := l.index[key]; ok {
+		heap.Remove(&l.items, item.index)
+		delete(l.index, key)
+	}
+}
+
+func (l *lfuEvictor[K]) victim(exclude K) (K, bool) {
+	var zero K
+	switch {
+	case len(l.items) == 0:
+		return zero, false
+	case l.items[0].key != exclude:
+		return l.items[0].key, true
+	}
+	// The root is excluded, so the next candidate is the smaller child.
+	best := -1
+	for _, i := range []int{1, 2} {
+		if i < len(l.items) && (best < 0 || l.items.Less(i, best)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return zero, false
+	}
+	return l.items[best].key, true
+}
+
+type lfuHeap[K comparable] []*lfuItem[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K]) Push(x any) {
+	item := x.(*lfuItem[K])
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap[K]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// entryOverhead approximates the bookkeeping cost of one entry (map slot,
+// item struct and eviction node) in bytes.
+const entryOverhead = 64
+
+// EstimateSize is the default Config.Sizer. It counts the shallow size of the
+// key and value plus the bytes of any strings they contain, and a fixed
+// per-entry overhead. It does not follow pointers, slices or maps.
+func EstimateSize(key, value any) int64 {
+	return entryOverhead + shallowSize(reflect.ValueOf(key)) + shallowSize(reflect.ValueOf(value))
+}
+
+func shallowSize(v reflect.Value) int64 {
+	if !v.IsValid() {
+		return 0
+	}
+	size := int64(v.Type().Size())
+	switch v.Kind() {
+	case reflect.String:
+		size += int64(v.Len())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.Kind() == reflect.String {
+				size += int64(f.Len())
+			}
+		}
+	}
+	return size
+}
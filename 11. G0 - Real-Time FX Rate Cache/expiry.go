@@ -0,0 +1,93 @@
+package fxcache
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryHeap is a min-heap of entries ordered by the time the janitor next
+// has to look at them (cacheItem.wakeAt). It lets a sweep touch only the
+// entries that are due instead of scanning the whole map.
+type expiryHeap[K comparable, V any] []*cacheItem[K, V]
+
+func (h expiryHeap[K, V]) Len() int           { return len(h) }
+func (h expiryHeap[K, V]) Less(i, j int) bool { return h[i].wakeAt < h[j].wakeAt }
+
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	item := x.(*cacheItem[K, V])
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// scheduleLocked sets when the janitor next has to look at item: at the start
+// of its refresh-ahead window, or else at its expiry. It re-arms the janitor
+// if item is now the earliest deadline. It must be called with c.mu held.
+func (c *TTLCache[K, V]) scheduleLocked(item *cacheItem[K, V], now int64) {
+	item.wakeAt = item.ExpiresAt
+	if ahead := c.refreshAhead.Milliseconds(); ahead > 0 && item.ExpiresAt-ahead > now {
+		item.wakeAt = item.ExpiresAt - ahead
+	}
+
+	if item.index >= 0 {
+		heap.Fix(&c.expiry, item.index)
+	} else {
+		heap.Push(&c.expiry, item)
+	}
+	if item.index == 0 && item.wakeAt < c.janitorAt {
+		c.armJanitorLocked(now)
+	}
+}
+
+// sweepLocked handles every entry whose wakeAt has passed: expired entries
+// are removed, and entries entering their refresh-ahead window are returned
+// for a refresh if they were read. It must be called with c.mu held.
+func (c *TTLCache[K, V]) sweepLocked(now int64) (refresh []K) {
+	for len(c.expiry) > 0 && c.expiry[0].wakeAt <= now {
+		item := c.expiry[0]
+		if now >= item.ExpiresAt {
+			c.removeLocked(item.key, ReasonExpired)
+			continue
+		}
+		if item.read.Load() {
+			refresh = append(refresh, item.key)
+		}
+		item.wakeAt = item.ExpiresAt
+		heap.Fix(&c.expiry, 0)
+	}
+	return refresh
+}
+
+// armJanitorLocked sets the janitor timer to the earliest deadline in the
+// heap, but no sooner than one janitor interval after the previous sweep, so
+// that expirations close together are handled in one sweep. With nothing
+// scheduled the janitor sleeps for the default TTL, or until a Set re-arms it.
+// It must be called with c.mu held.
+func (c *TTLCache[K, V]) armJanitorLocked(now int64) {
+	if c.janitor == nil || c.janitorStopped {
+		return
+	}
+	at := now + c.defaultTTL.Milliseconds()
+	if len(c.expiry) > 0 {
+		at = c.expiry[0].wakeAt
+	}
+	at = max(at, c.lastSweep+c.janitorInterval.Milliseconds())
+
+	c.janitorAt = at
+	c.janitor.Reset(time.Duration(at-now) * time.Millisecond)
+}
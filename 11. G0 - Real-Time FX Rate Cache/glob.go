@@ -0,0 +1,86 @@
+package fxcache
+
+// MatchGlob reports whether key matches a Redis-style glob pattern: '*'
+// matches any run of characters (including '/'), '?' any single character,
+// "[abc]", "[a-z]" and "[^a]" a character class, and '\' escapes the next
+// character. So "USD/*" matches every pair with base currency USD.
+func MatchGlob(pattern, key string) bool {
+	p, k := []rune(pattern), []rune(key)
+	// Backtracking positions for the most recent '*'.
+	star, starKey := -1, 0
+	pi, ki := 0, 0
+	for ki < len(k) {
+		if pi < len(p) {
+			switch p[pi] {
+			case '*':
+				star, starKey = pi, ki
+				pi++
+				continue
+			case '?':
+				pi++
+				ki++
+				continue
+			case '[':
+				if end, ok := matchClass(p, pi, k[ki]); ok {
+					pi = end
+					ki++
+					continue
+				}
+			case '\\':
+				if pi+1 < len(p) && p[pi+1] == k[ki] {
+					pi += 2
+					ki++
+					continue
+				}
+			default:
+				if p[pi] == k[ki] {
+					pi++
+					ki++
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		// Let the last '*' swallow one more character and retry.
+		starKey++
+		pi, ki = star+1, starKey
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// matchClass matches r against the character class starting at p[start]
+// ('['). It returns the index after the closing ']' and whether r matched.
+// An unterminated class matches nothing.
+func matchClass(p []rune, start int, r rune) (int, bool) {
+	i := start + 1
+	negate := i < len(p) && p[i] == '^'
+	if negate {
+		i++
+	}
+	matched := false
+	for first := true; i < len(p) && (first || p[i] != ']'); first = false {
+		lo := p[i]
+		if lo == '\\' && i+1 < len(p) {
+			i++
+			lo = p[i]
+		}
+		hi := lo
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			hi = p[i+2]
+			i += 2
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+		i++
+	}
+	if i >= len(p) {
+		return 0, false
+	}
+	return i + 1, matched != negate
+}
@@ -0,0 +1,34 @@
+package fxcache
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"*", "USD/THB", true},
+		{"*", "", true},
+		{"USD/*", "USD/THB", true},
+		{"USD/*", "EUR/USD", false},
+		{"*/USD", "EUR/USD", true},
+		{"*USD*", "GBPUSD", true},
+		{"USD/???", "USD/THB", true},
+		{"USD/???", "USD/TH", false},
+		{"USD/[EJ]*", "USD/JPY", true},
+		{"USD/[EJ]*", "USD/THB", false},
+		{"USD/[^EJ]*", "USD/THB", true},
+		{"[A-C]*", "CAD/USD", true},
+		{"[A-C]*", "USD/CAD", false},
+		{"USD\\*", "USD*", true},
+		{"USD\\*", "USDX", false},
+		{"USD/[ab", "USD/a", false},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYbZ", false},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.key); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
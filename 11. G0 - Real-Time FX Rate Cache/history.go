@@ -0,0 +1,373 @@
+package fxcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Point is one recorded rate.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Bar is the open, high, low and close of the points recorded in one
+// interval starting at Start.
+type Bar struct {
+	Start                  time.Time
+	Open, High, Low, Close float64
+	// Count is the number of points in the interval.
+	Count int
+}
+
+// HistoryConfig holds the settings used by NewRateHistory. Zero values fall
+// back to the defaults.
+type HistoryConfig struct {
+	// MaxAge drops points older than this. Zero keeps them forever.
+	MaxAge time.Duration
+	// MaxPoints caps the number of points kept per key, dropping the oldest
+	// first. Zero means unbounded.
+	MaxPoints int
+	// Path is a file every point is appended to and that is replayed when
+	// the history is created. Empty keeps the history in memory only.
+	Path string
+	// Sync and SyncInterval control when the file is flushed, as
+	// Config.WALSync and Config.WALSyncInterval do for the write-ahead log.
+	Sync         WALSyncPolicy
+	SyncInterval time.Duration
+	// Clock is the time source for recorded points and retention. It
+	// defaults to SystemClock and should be the clock of the cache the
+	// history is attached to.
+	Clock Clock
+	// OnError receives errors from appending to the file and from background
+	// syncs. It defaults to logging them.
+	OnError func(error)
+}
+
+// RateHistory is a time series of the rates of each key, so past rates can
+// be looked up after the cache has replaced or expired them. It is safe for
+// concurrent use.
+type RateHistory struct {
+	mu     sync.RWMutex
+	series map[string][]Point
+
+	maxAge    time.Duration
+	maxPoints int
+	clock     Clock
+	onError   func(error)
+
+	// log is the backing file, or nil for an in-memory history.
+	log       *wal
+	path      string
+	policy    WALSyncPolicy
+	stopSync  chan struct{}
+	syncDone  sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// historyRecord is the JSON payload of one point in the backing file. Time
+// is in Unix milliseconds, like the cache's expiry times.
+type historyRecord struct {
+	Key   string  `json:"key"`
+	Value float64 `json:"value"`
+	Time  int64   `json:"time"`
+}
+
+// NewRateHistory returns a history with the given retention, replaying
+// cfg.Path if it is set. Call Close to release the file.
+func NewRateHistory(cfg HistoryConfig) (*RateHistory, error) {
+	if cfg.MaxAge < 0 || cfg.MaxPoints < 0 {
+		return nil, fmt.Errorf("history retention must not be negative")
+	}
+	if cfg.Sync < WALSyncAlways || cfg.Sync > WALSyncNever {
+		return nil, fmt.Errorf("unknown history sync policy %d", cfg.Sync)
+	}
+	if cfg.SyncInterval < 0 {
+		return nil, fmt.Errorf("history sync interval must not be negative")
+	}
+	if cfg.SyncInterval == 0 {
+		cfg.SyncInterval = DefaultWALSyncInterval
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock{}
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = logError
+	}
+
+	h := &RateHistory{
+		series:    make(map[string][]Point),
+		maxAge:    cfg.MaxAge,
+		maxPoints: cfg.MaxPoints,
+		clock:     cfg.Clock,
+		onError:   cfg.OnError,
+		path:      cfg.Path,
+		policy:    cfg.Sync,
+	}
+	if cfg.Path == "" {
+		return h, nil
+	}
+
+	log, err := openWAL(cfg.Path, cfg.Sync, h.replayRecord)
+	if err != nil {
+		return nil, fmt.Errorf("open history %s: %w", cfg.Path, err)
+	}
+	h.log = log
+	for key := range h.series {
+		h.trimLocked(key, h.clock.Now())
+	}
+	if cfg.Sync == WALSyncInterval {
+		h.startSync(cfg.SyncInterval)
+	}
+	return h, nil
+}
+
+func (h *RateHistory) replayRecord(payload []byte) error {
+	var rec historyRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return err
+	}
+	h.insertLocked(rec.Key, Point{Time: time.UnixMilli(rec.Time), Value: rec.Value})
+	return nil
+}
+
+// Attach records every value stored in cache, including loads and refreshes,
+// at the time it is stored.
+func (h *RateHistory) Attach(cache *FXRateCache) {
+	cache.OnSet(func(key string, value float64, _ time.Time) {
+		h.Record(key, value, h.clock.Now())
+	})
+}
+
+// Record adds the rate of key at the given time. Points may arrive out of
+// order. Points outside the retention limits are dropped.
+func (h *RateHistory) Record(key string, value float64, at time.Time) {
+	// Points are kept at the millisecond precision of the backing file.
+	at = time.UnixMilli(at.UnixMilli())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.clock.Now()
+	if h.maxAge > 0 && at.Before(now.Add(-h.maxAge)) {
+		return
+	}
+	h.insertLocked(key, Point{Time: at, Value: value})
+	h.trimLocked(key, now)
+	if h.log != nil {
+		h.appendLocked(historyRecord{Key: key, Value: value, Time: at.UnixMilli()})
+	}
+}
+
+// insertLocked adds p to the series of key, keeping it sorted by time. It
+// must be called with h.mu held.
+func (h *RateHistory) insertLocked(key string, p Point) {
+	points := h.series[key]
+	i := len(points)
+	if i > 0 && p.Time.Before(points[i-1].Time) {
+		i = sort.Search(len(points), func(j int) bool { return points[j].Time.After(p.Time) })
+	}
+	h.series[key] = slices.Insert(points, i, p)
+}
+
+// trimLocked applies the retention limits to the series of key. It must be
+// called with h.mu held.
+func (h *RateHistory) trimLocked(key string, now time.Time) {
+	points := h.series[key]
+	drop := 0
+	if h.maxAge > 0 {
+		cutoff := now.Add(-h.maxAge)
+		drop = sort.Search(len(points), func(j int) bool { return !points[j].Time.Before(cutoff) })
+	}
+	if h.maxPoints > 0 {
+		drop = max(drop, len(points)-h.maxPoints)
+	}
+	switch {
+	case drop == len(points):
+		delete(h.series, key)
+	case drop > cap(points)/2:
+		// Most of the backing array is dropped; copy the rest so that the
+		// array can be freed.
+		h.series[key] = slices.Clone(points[drop:])
+	case drop > 0:
+		// Reslice: the dropped points stay in the backing array until the
+		// next insert that outgrows it reallocates, so steady trimming costs
+		// no copy per point.
+		h.series[key] = points[drop:]
+	}
+}
+
+func (h *RateHistory) appendLocked(rec historyRecord) {
+	payload, err := json.Marshal(rec)
+	if err == nil {
+		err = h.log.append(payload)
+	}
+	if err != nil {
+		h.onError(fmt.Errorf("append to history %s: %w", h.path, err))
+	}
+}
+
+// Range returns the points of key recorded in [from, to), oldest first.
+func (h *RateHistory) Range(key string, from, to time.Time) []Point {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	points := h.series[key]
+	lo := sort.Search(len(points), func(j int) bool { return !points[j].Time.Before(from) })
+	hi := sort.Search(len(points), func(j int) bool { return !points[j].Time.Before(to) })
+	if lo >= hi {
+		return nil
+	}
+	return slices.Clone(points[lo:hi])
+}
+
+// At returns the rate of key as of t: the last point recorded at or before
+// t. It reports false if there is none.
+func (h *RateHistory) At(key string, t time.Time) (Point, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	points := h.series[key]
+	i := sort.Search(len(points), func(j int) bool { return points[j].Time.After(t) })
+	if i == 0 {
+		return Point{}, false
+	}
+	return points[i-1], true
+}
+
+// OHLC aggregates the points of key in [from, to) into bars of the given
+// interval, starting at from. Intervals without points are left out.
+func (h *RateHistory) OHLC(key string, from, to time.Time, interval time.Duration) ([]Bar, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("OHLC interval must be positive")
+	}
+	var bars []Bar
+	for _, p := range h.Range(key, from, to) {
+		start := from.Add(p.Time.Sub(from) / interval * interval)
+		if n := len(bars); n > 0 && bars[n-1].Start.Equal(start) {
+			bar := &bars[n-1]
+			bar.High = max(bar.High, p.Value)
+			bar.Low = min(bar.Low, p.Value)
+			bar.Close = p.Value
+			bar.Count++
+			continue
+		}
+		bars = append(bars, Bar{Start: start, Open: p.Value, High: p.Value, Low: p.Value, Close: p.Value, Count: 1})
+	}
+	return bars, nil
+}
+
+// Keys returns the keys that have points, in no particular order.
+func (h *RateHistory) Keys() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Prune applies MaxAge to every key. Record only prunes the key it writes,
+// so keys that are no longer updated keep their points until Prune is
+// called.
+func (h *RateHistory) Prune() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.clock.Now()
+	for key := range h.series {
+		h.trimLocked(key, now)
+	}
+}
+
+// Compact prunes the history and rewrites the backing file with only the
+// points that are kept, so it does not grow without bound.
+func (h *RateHistory) Compact() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.log == nil {
+		return fmt.Errorf("history is not file-backed")
+	}
+	now := h.clock.Now()
+	for key := range h.series {
+		h.trimLocked(key, now)
+	}
+
+	err := writeFileAtomic(h.path, func(w io.Writer) error {
+		if _, err := w.Write(walHeader); err != nil {
+			return err
+		}
+		for key, points := range h.series {
+			for _, p := range points {
+				payload, err := json.Marshal(historyRecord{Key: key, Value: p.Value, Time: p.Time.UnixMilli()})
+				if err != nil {
+					return err
+				}
+				if _, err := w.Write(walFrame(payload)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("compact history %s: %w", h.path, err)
+	}
+
+	// Switch to the new file; the old one was renamed over.
+	log, err := openWAL(h.path, h.policy, func([]byte) error { return nil })
+	if err != nil {
+		return fmt.Errorf("reopen history %s: %w", h.path, err)
+	}
+	old := h.log
+	h.log = log
+	return old.close()
+}
+
+// startSync fsyncs the file every interval until Close.
+func (h *RateHistory) startSync(interval time.Duration) {
+	h.stopSync = make(chan struct{})
+	timer := h.clock.NewTimer(interval)
+	h.syncDone.Add(1)
+	go func() {
+		defer h.syncDone.Done()
+		for {
+			select {
+			case <-timer.C():
+				// The read lock keeps Compact from swapping the file mid-sync.
+				h.mu.RLock()
+				err := h.log.sync()
+				h.mu.RUnlock()
+				if err != nil {
+					h.onError(fmt.Errorf("sync history %s: %w", h.path, err))
+				}
+				timer.Reset(interval)
+			case <-h.stopSync:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Close syncs and closes the backing file. The in-memory history stays
+// readable, but points recorded after Close are no longer written to the
+// file. It is safe to call more than once.
+func (h *RateHistory) Close() error {
+	var err error
+	h.closeOnce.Do(func() {
+		if h.stopSync != nil {
+			close(h.stopSync)
+			h.syncDone.Wait()
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.log != nil {
+			err = h.log.close()
+			h.log = nil
+		}
+	})
+	return err
+}
@@ -0,0 +1,183 @@
+package fxcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRateHistory(t *testing.T) {
+	cache, clock := newTestCache(t)
+	history, err := NewRateHistory(HistoryConfig{Clock: clock})
+	if err != nil {
+		t.Fatalf("NewRateHistory: %v", err)
+	}
+	history.Attach(cache)
+
+	start := clock.Now()
+	for _, rate := range []float64{36.50, 36.55, 36.40, 36.45, 36.60, 36.58} {
+		cache.Set("USD/THB", rate)
+		clock.Advance(20 * time.Second)
+	}
+	// A late point is kept in time order.
+	history.Record("USD/THB", 36.52, start.Add(30*time.Second))
+
+	at := func(offset time.Duration) float64 {
+		t.Helper()
+		p, ok := history.At("USD/THB", start.Add(offset))
+		if !ok {
+			t.Fatalf("At(+%v) found nothing", offset)
+		}
+		return p.Value
+	}
+	if got := at(0); got != 36.50 {
+		t.Fatalf("At(+0) = %v, want the point set at that instant", got)
+	}
+	if got := at(39 * time.Second); got != 36.52 {
+		t.Fatalf("At(+39s) = %v, want the late point from +30s", got)
+	}
+	if got := at(time.Hour); got != 36.58 {
+		t.Fatalf("At(+1h) = %v, want the last point", got)
+	}
+	if _, ok := history.At("USD/THB", start.Add(-time.Millisecond)); ok {
+		t.Fatal("At before the first point found one")
+	}
+
+	points := history.Range("USD/THB", start.Add(20*time.Second), start.Add(60*time.Second))
+	if len(points) != 3 || points[0].Value != 36.55 || points[1].Value != 36.52 || points[2].Value != 36.40 {
+		t.Fatalf("Range = %v, want 36.55, 36.52 and 36.40", points)
+	}
+
+	bars, err := history.OHLC("USD/THB", start, start.Add(2*time.Minute), time.Minute)
+	if err != nil {
+		t.Fatalf("OHLC: %v", err)
+	}
+	want := []Bar{
+		{Start: start, Open: 36.50, High: 36.55, Low: 36.40, Close: 36.40, Count: 4},
+		{Start: start.Add(time.Minute), Open: 36.45, High: 36.60, Low: 36.45, Close: 36.58, Count: 3},
+	}
+	if len(bars) != len(want) {
+		t.Fatalf("OHLC = %+v, want %+v", bars, want)
+	}
+	for i := range want {
+		if !bars[i].Start.Equal(want[i].Start) || bars[i].Open != want[i].Open || bars[i].High != want[i].High ||
+			bars[i].Low != want[i].Low || bars[i].Close != want[i].Close || bars[i].Count != want[i].Count {
+			t.Fatalf("bar %d = %+v, want %+v", i, bars[i], want[i])
+		}
+	}
+	if _, err := history.OHLC("USD/THB", start, start.Add(time.Minute), 0); err == nil {
+		t.Fatal("OHLC with a zero interval succeeded")
+	}
+}
+
+func TestRateHistoryRetention(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	history, err := NewRateHistory(HistoryConfig{Clock: clock, MaxAge: time.Minute, MaxPoints: 3})
+	if err != nil {
+		t.Fatalf("NewRateHistory: %v", err)
+	}
+
+	for i := range 5 {
+		history.Record("USD/THB", float64(i), clock.Now())
+		clock.Advance(time.Second)
+	}
+	if points := history.Range("USD/THB", time.Time{}, clock.Now()); len(points) != 3 || points[0].Value != 2 {
+		t.Fatalf("points = %v, want the newest 3", points)
+	}
+
+	history.Record("EUR/USD", 1.08, clock.Now())
+	clock.Advance(2 * time.Minute)
+	history.Record("USD/THB", 36.5, clock.Now())
+	if points := history.Range("USD/THB", time.Time{}, clock.Now().Add(time.Second)); len(points) != 1 {
+		t.Fatalf("USD/THB points = %v, want only the fresh one", points)
+	}
+	// EUR/USD was not written again, so only Prune drops it.
+	if len(history.Keys()) != 2 {
+		t.Fatalf("Keys = %v before Prune, want both", history.Keys())
+	}
+	history.Prune()
+	if keys := history.Keys(); len(keys) != 1 || keys[0] != "USD/THB" {
+		t.Fatalf("Keys = %v after Prune, want USD/THB", keys)
+	}
+	// Points older than MaxAge are not recorded at all.
+	history.Record("GBP/USD", 1.27, clock.Now().Add(-2*time.Minute))
+	if _, ok := history.At("GBP/USD", clock.Now()); ok {
+		t.Fatal("a point older than MaxAge was recorded")
+	}
+}
+
+func TestRateHistoryTrimDoesNotCopy(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	history, err := NewRateHistory(HistoryConfig{Clock: clock, MaxPoints: 100})
+	if err != nil {
+		t.Fatalf("NewRateHistory: %v", err)
+	}
+	record := func() {
+		clock.Advance(time.Second)
+		history.Record("USD/THB", 36.5, clock.Now())
+	}
+	for range 200 {
+		record()
+	}
+	// At the cap, each Record drops one point; the series is only copied
+	// when an insert outgrows its backing array.
+	if allocs := testing.AllocsPerRun(1000, record); allocs >= 1 {
+		t.Errorf("Record allocates %v times per call at the cap, want amortized to less than 1", allocs)
+	}
+	if points := history.Range("USD/THB", time.Time{}, clock.Now().Add(time.Second)); len(points) != 100 {
+		t.Fatalf("%d points, want 100", len(points))
+	}
+}
+
+func TestRateHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.log")
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	open := func() *RateHistory {
+		t.Helper()
+		h, err := NewRateHistory(HistoryConfig{Path: path, Clock: clock, MaxAge: time.Hour})
+		if err != nil {
+			t.Fatalf("NewRateHistory: %v", err)
+		}
+		return h
+	}
+
+	history := open()
+	start := clock.Now()
+	history.Record("USD/THB", 36.5, start)
+	history.Record("USD/THB", 36.6, start.Add(time.Minute))
+	clock.Advance(90 * time.Minute)
+	history.Record("USD/THB", 36.7, clock.Now())
+	if err := history.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := history.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	// Replay restores the points still within MaxAge.
+	history = open()
+	if p, ok := history.At("USD/THB", start.Add(time.Hour)); ok {
+		t.Fatalf("At(+1h) = %v after replay, want the expired points dropped", p)
+	}
+	if p, ok := history.At("USD/THB", clock.Now()); !ok || p.Value != 36.7 {
+		t.Fatalf("At(now) = %v, %v after replay, want 36.7", p, ok)
+	}
+
+	before, _ := os.Stat(path)
+	if err := history.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Fatalf("Compact left %d bytes, want less than %d", after.Size(), before.Size())
+	}
+	history.Record("EUR/USD", 1.08, clock.Now())
+	history.Close()
+
+	history = open()
+	defer history.Close()
+	if len(history.Keys()) != 2 {
+		t.Fatalf("Keys = %v after compaction and replay, want USD/THB and EUR/USD", history.Keys())
+	}
+}
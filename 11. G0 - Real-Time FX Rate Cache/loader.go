@@ -0,0 +1,193 @@
+package fxcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoLoader is returned by GetOrLoad when it is called without a loader
+// and none was registered with SetLoader.
+var ErrNoLoader = errors.New("no loader given and none registered")
+
+// LoaderFunc loads the value of a key that is missing from the cache, e.g. by
+// calling an FX rate provider.
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// loadCall is an in-flight or completed loader call shared by every caller
+// that missed on the same key.
+type loadCall[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// failedLoad is a cached loader error, kept for Config.NegativeTTL.
+type failedLoad struct {
+	err error
+	// expiresAt is the Unix timestamp in milliseconds when the error is dropped.
+	expiresAt int64
+}
+
+// GetOrLoad returns the value for key, calling loader to fetch it on a miss.
+// Concurrent misses for the same key share a single loader call, so a burst of
+// requests after expiry reaches the provider only once. A loaded value is
+// stored with the optional ttl, or the cache's default TTL.
+//
+// Loader errors are returned to every waiting caller and are not cached,
+// unless Config.NegativeTTL is set, in which case the error is returned
+// without calling the loader again until NegativeTTL has passed.
+//
+// The loader runs with the context of the caller that triggered it. Other
+// callers stop waiting when their own context is done. A nil loader means
+// the one registered with SetLoader. On a miss after Close it returns
+// ErrClosed.
+func (c *TTLCache[K, V]) GetOrLoad(ctx context.Context, key K, loader LoaderFunc[K, V], ttl ...time.Duration) (V, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	var zero V
+	c.loadMu.Lock()
+	if c.closed.Load() {
+		c.loadMu.Unlock()
+		return zero, ErrClosed
+	}
+	if loader == nil {
+		loader = c.loader
+	}
+	if loader == nil {
+		c.loadMu.Unlock()
+		return zero, ErrNoLoader
+	}
+	// Re-check under loadMu: a load may have finished since the first Get.
+	// lookup is used instead of Get because a stale hit would call refresh,
+	// which takes loadMu.
+	if value, _, ok, _ := c.lookup(key); ok {
+		c.loadMu.Unlock()
+		return value, nil
+	}
+	if err, ok := c.cachedFailure(key); ok {
+		c.loadMu.Unlock()
+		return zero, err
+	}
+
+	call, inFlight := c.loads[key]
+	if !inFlight {
+		call = &loadCall[V]{done: make(chan struct{})}
+		c.loads[key] = call
+	}
+	c.loadMu.Unlock()
+
+	if !inFlight {
+		c.load(ctx, key, loader, call, ttl...)
+		return call.val, call.err
+	}
+
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// load runs loader for key, stores the outcome and releases the waiters.
+func (c *TTLCache[K, V]) load(ctx context.Context, key K, loader LoaderFunc[K, V], call *loadCall[V], ttl ...time.Duration) {
+	defer func() {
+		c.loadMu.Lock()
+		delete(c.loads, key)
+		c.loadMu.Unlock()
+		close(call.done)
+	}()
+
+	// Reported to waiters if the loader panics.
+	call.err = fmt.Errorf("loader for key %v panicked", key)
+	call.val, call.err = loader(ctx, key)
+	if call.err != nil {
+		c.rememberFailure(key, call.err)
+		return
+	}
+	c.Set(key, call.val, ttl...)
+}
+
+// SetLoader registers the loader used to refresh stale entries in the
+// background (see Config.SoftTTL and Config.RefreshAhead) and by GetOrLoad
+// when it is called with a nil loader.
+func (c *TTLCache[K, V]) SetLoader(loader LoaderFunc[K, V]) {
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	c.loader = loader
+}
+
+// refresh reloads key in the background through the registered loader,
+// keeping its current TTL. It does nothing if no loader is registered, a load
+// for key is already in flight, a recent load of key failed, or the cache is
+// closed.
+func (c *TTLCache[K, V]) refresh(key K) {
+	c.mu.RLock()
+	item, ok := c.cache[key]
+	var ttl time.Duration
+	if ok {
+		ttl = item.ttl
+	}
+	c.mu.RUnlock()
+	if !ok {
+		return
+	}
+	if _, failed := c.cachedFailure(key); failed {
+		return
+	}
+
+	c.loadMu.Lock()
+	loader := c.loader
+	if _, inFlight := c.loads[key]; inFlight || loader == nil || c.closed.Load() {
+		c.loadMu.Unlock()
+		return
+	}
+	call := &loadCall[V]{done: make(chan struct{})}
+	c.loads[key] = call
+	c.refreshes.Add(1)
+	c.loadMu.Unlock()
+
+	go func() {
+		defer c.refreshes.Done()
+		// A panic here would crash the process, since nobody called the
+		// loader; report it as a failed load instead.
+		defer func() {
+			if r := recover(); r != nil {
+				err := fmt.Errorf("loader for key %v panicked: %v", key, r)
+				c.rememberFailure(key, err)
+				c.onError(fmt.Errorf("refresh: %w", err))
+			}
+		}()
+		c.load(c.background, key, loader, call, ttl)
+	}()
+}
+
+// cachedFailure returns a loader error cached for key that has not expired.
+func (c *TTLCache[K, V]) cachedFailure(key K) (error, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	failure, ok := c.failures[key]
+	if !ok || c.clock.Now().UnixMilli() >= failure.expiresAt {
+		return nil, false
+	}
+	return failure.err, true
+}
+
+// rememberFailure caches a loader error when Config.NegativeTTL is set.
+func (c *TTLCache[K, V]) rememberFailure(key K, err error) {
+	if c.negativeTTL <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures[key] = failedLoad{
+		err:       err,
+		expiresAt: c.clock.Now().Add(c.negativeTTL).UnixMilli(),
+	}
+}
@@ -0,0 +1,246 @@
+package fxcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadCollapsesConcurrentMisses(t *testing.T) {
+	cache, _ := newTestCache(t)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (float64, error) {
+		calls.Add(1)
+		<-release
+		return 36.5, nil
+	}
+
+	const callers = 50
+	var wg sync.WaitGroup
+	results := make(chan float64, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := cache.GetOrLoad(context.Background(), "USD/THB", loader)
+			if err != nil {
+				t.Errorf("GetOrLoad: %v", err)
+			}
+			results <- val
+		}()
+	}
+
+	// Wait until the first caller is inside the loader, then let it finish.
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("loader called %d times, want 1", got)
+	}
+	for val := range results {
+		if val != 36.5 {
+			t.Fatalf("GetOrLoad = %v, want 36.5", val)
+		}
+	}
+	if val, ok := cache.Get("USD/THB"); !ok || val != 36.5 {
+		t.Fatalf("Get after load = (%v, %v), want (36.5, true)", val, ok)
+	}
+}
+
+func TestGetOrLoadErrors(t *testing.T) {
+	errProvider := errors.New("provider down")
+	var calls int
+	loader := func(ctx context.Context, key string) (float64, error) {
+		calls++
+		return 0, errProvider
+	}
+
+	t.Run("not cached by default", func(t *testing.T) {
+		cache, _ := newTestCache(t)
+		calls = 0
+		for i := 0; i < 2; i++ {
+			if _, err := cache.GetOrLoad(context.Background(), "EUR/USD", loader); !errors.Is(err, errProvider) {
+				t.Fatalf("GetOrLoad error = %v, want %v", err, errProvider)
+			}
+		}
+		if calls != 2 {
+			t.Fatalf("loader called %d times, want 2", calls)
+		}
+	})
+
+	t.Run("cached for NegativeTTL", func(t *testing.T) {
+		clock := NewManualClock(time.Unix(0, 0))
+		cache, err := NewTTLCacheWithConfig[string, float64](Config{
+			DefaultTTL:      time.Hour,
+			JanitorInterval: time.Hour,
+			NegativeTTL:     time.Second,
+			Clock:           clock,
+		})
+		if err != nil {
+			t.Fatalf("NewTTLCacheWithConfig: %v", err)
+		}
+		defer cache.StopJanitor()
+
+		calls = 0
+		for i := 0; i < 2; i++ {
+			if _, err := cache.GetOrLoad(context.Background(), "EUR/USD", loader); !errors.Is(err, errProvider) {
+				t.Fatalf("GetOrLoad error = %v, want %v", err, errProvider)
+			}
+		}
+		if calls != 1 {
+			t.Fatalf("loader called %d times within NegativeTTL, want 1", calls)
+		}
+
+		clock.Advance(time.Second)
+		cache.GetOrLoad(context.Background(), "EUR/USD", loader)
+		if calls != 2 {
+			t.Fatalf("loader called %d times after NegativeTTL, want 2", calls)
+		}
+	})
+}
+
+func TestSoftTTLServesStaleAndRefreshes(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Hour,
+		JanitorInterval: time.Hour,
+		SoftTTL:         100 * time.Millisecond,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	var calls atomic.Int32
+	cache.SetLoader(func(ctx context.Context, key string) (float64, error) {
+		calls.Add(1)
+		return 36.6, nil
+	})
+	cache.Set("USD/THB", 36.5, time.Second)
+
+	clock.Advance(50 * time.Millisecond)
+	cache.Get("USD/THB")
+	cache.refreshes.Wait()
+	if got := calls.Load(); got != 0 {
+		t.Fatalf("loader called %d times before the soft TTL, want 0", got)
+	}
+
+	clock.Advance(50 * time.Millisecond)
+	if val, ok := cache.Get("USD/THB"); !ok || val != 36.5 {
+		t.Fatalf("stale Get = (%v, %v), want (36.5, true)", val, ok)
+	}
+	cache.refreshes.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("loader called %d times after the soft TTL, want 1", got)
+	}
+
+	// The refreshed entry keeps the original one-second TTL.
+	val, ttl, ok := cache.GetWithExpiry("USD/THB")
+	if !ok || val != 36.6 || ttl != time.Second {
+		t.Fatalf("GetWithExpiry after refresh = (%v, %v, %v), want (36.6, 1s, true)", val, ttl, ok)
+	}
+}
+
+func TestRefreshAheadReloadsHotKeys(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Second,
+		JanitorInterval: 100 * time.Millisecond,
+		RefreshAhead:    200 * time.Millisecond,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	loaded := make(chan string, 2)
+	cache.SetLoader(func(ctx context.Context, key string) (float64, error) {
+		loaded <- key
+		return 1.09, nil
+	})
+	cache.Set("EUR/USD", 1.08) // hot: read below
+	cache.Set("GBP/USD", 1.25) // cold: never read
+	cache.Get("EUR/USD")
+
+	// The janitor wakes at 800ms, when both entries enter the window.
+	clock.Advance(800 * time.Millisecond)
+	clock.BlockUntil(1)
+	cache.refreshes.Wait()
+
+	if got := len(loaded); got != 1 {
+		t.Fatalf("loader called %d times, want 1", got)
+	}
+	if key := <-loaded; key != "EUR/USD" {
+		t.Fatalf("refreshed %q, want EUR/USD", key)
+	}
+	if val, ttl, ok := cache.GetWithExpiry("EUR/USD"); !ok || val != 1.09 || ttl <= 200*time.Millisecond {
+		t.Fatalf("GetWithExpiry after refresh-ahead = (%v, %v, %v), want (1.09, >200ms, true)", val, ttl, ok)
+	}
+}
+
+func TestGetOrLoadWithoutLoader(t *testing.T) {
+	cache, _ := newTestCache(t)
+	if _, err := cache.GetOrLoad(context.Background(), "USD/THB", nil); !errors.Is(err, ErrNoLoader) {
+		t.Fatalf("GetOrLoad without a loader: err = %v, want ErrNoLoader", err)
+	}
+}
+
+func TestRefreshRecoversLoaderPanic(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var mu sync.Mutex
+	var errs []error
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Hour,
+		JanitorInterval: time.Hour,
+		SoftTTL:         time.Second,
+		NegativeTTL:     time.Minute,
+		Clock:           clock,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	var calls atomic.Int32
+	cache.SetLoader(func(ctx context.Context, key string) (float64, error) {
+		calls.Add(1)
+		panic("provider bug")
+	})
+	cache.Set("USD/THB", 36.5)
+	clock.Advance(time.Second)
+	if val, ok := cache.Get("USD/THB"); !ok || val != 36.5 {
+		t.Fatalf("stale Get = (%v, %v), want (36.5, true)", val, ok)
+	}
+	cache.refreshes.Wait()
+
+	mu.Lock()
+	if len(errs) != 1 {
+		t.Errorf("OnError got %v, want the panic reported once", errs)
+	}
+	mu.Unlock()
+	// The panic counts as a failed load, so NegativeTTL holds off retries.
+	cache.Get("USD/THB")
+	cache.refreshes.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Errorf("loader called %d times, want 1", got)
+	}
+	if val, _ := cache.Get("USD/THB"); val != 36.5 {
+		t.Errorf("Get = %v after the failed refresh, want the old 36.5", val)
+	}
+}
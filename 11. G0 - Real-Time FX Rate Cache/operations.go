@@ -0,0 +1,233 @@
+package fxcache
+
+import "time"
+
+// keyValue is a copied entry handed out by Range.
+type keyValue[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Has reports whether key has a live entry. Unlike Get it does not count as a
+// read for eviction or refresh.
+func (c *TTLCache[K, V]) Has(key K) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	item, ok := c.cache[key]
+	return ok && c.clock.Now().UnixMilli() < item.ExpiresAt
+}
+
+// Len returns the number of live entries. Expired entries that the janitor
+// has not removed yet are not counted.
+func (c *TTLCache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now().UnixMilli()
+	n := 0
+	for _, item := range c.cache {
+		if now < item.ExpiresAt {
+			n++
+		}
+	}
+	return n
+}
+
+// Keys returns the keys of all live entries in no particular order.
+func (c *TTLCache[K, V]) Keys() []K {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.clock.Now().UnixMilli()
+	keys := make([]K, 0, len(c.cache))
+	for key, item := range c.cache {
+		if now < item.ExpiresAt {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Range calls fn for each live entry until fn returns false. It works on a
+// copy of the entries taken under the lock, so fn may call back into the
+// cache, and entries set or removed during the iteration may or may not be
+// visited.
+func (c *TTLCache[K, V]) Range(fn func(key K, value V) bool) {
+	c.mu.RLock()
+	now := c.clock.Now().UnixMilli()
+	entries := make([]keyValue[K, V], 0, len(c.cache))
+	for key, item := range c.cache {
+		if now < item.ExpiresAt {
+			entries = append(entries, keyValue[K, V]{key: key, value: item.Value})
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, e := range entries {
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Clear removes every entry. Live entries are reported to the OnEvicted
+// callbacks as deleted and expired ones as expired.
+func (c *TTLCache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return
+	}
+
+	now := c.clock.Now().UnixMilli()
+	for key, item := range c.cache {
+		reason := ReasonDeleted
+		if now >= item.ExpiresAt {
+			reason = ReasonExpired
+		}
+		c.removeLocked(key, reason)
+	}
+	clear(c.failures)
+}
+
+// Touch extends the TTL of a live entry without changing its value, using the
+// optional ttl or the cache's default TTL from now. It counts as a use for
+// the eviction policy, like Get. It reports whether the entry was found.
+func (c *TTLCache[K, V]) Touch(key K, ttl ...time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return false
+	}
+
+	now := c.clock.Now().UnixMilli()
+	item, ok := c.cache[key]
+	if !ok || now >= item.ExpiresAt {
+		return false
+	}
+	c.setExpiryLocked(item, now, now+c.effectiveTTL(ttl).Milliseconds())
+	c.accessLocked(key)
+	return true
+}
+
+// SetUntil stores value with an absolute expiry time rather than a TTL, so
+// copies of an entry on several nodes expire together. It does nothing and
+// returns false if expiresAt has already passed.
+func (c *TTLCache[K, V]) SetUntil(key K, value V, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return false
+	}
+
+	now := c.clock.Now().UnixMilli()
+	if now >= expiresAt.UnixMilli() {
+		return false
+	}
+	c.storeLocked(key, value, now, expiresAt.UnixMilli())
+	return true
+}
+
+// DefaultTTL returns the TTL given to entries stored without one.
+func (c *TTLCache[K, V]) DefaultTTL() time.Duration {
+	return c.defaultTTL
+}
+
+// SetMany stores all entries under a single lock, with the optional ttl or
+// the cache's default TTL.
+func (c *TTLCache[K, V]) SetMany(entries map[K]V, ttl ...time.Duration) {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return
+	}
+
+	now := c.clock.Now().UnixMilli()
+	expiresAt := now + c.effectiveTTL(ttl).Milliseconds()
+	for key, value := range entries {
+		c.storeLocked(key, value, now, expiresAt)
+	}
+}
+
+// GetMany looks up several keys under a single lock and returns the live
+// entries found. Missing and expired keys are left out of the result.
+func (c *TTLCache[K, V]) GetMany(keys []K) map[K]V {
+	found, _ := c.getMany(keys, false)
+	return found
+}
+
+// GetManyWithExpiry is GetMany that also returns the remaining TTL of each
+// entry found, all read at the same instant.
+func (c *TTLCache[K, V]) GetManyWithExpiry(keys []K) (map[K]V, map[K]time.Duration) {
+	return c.getMany(keys, true)
+}
+
+func (c *TTLCache[K, V]) getMany(keys []K, withTTL bool) (map[K]V, map[K]time.Duration) {
+	found := make(map[K]V, len(keys))
+	var ttls map[K]time.Duration
+	if withTTL {
+		ttls = make(map[K]time.Duration, len(keys))
+	}
+	var stale []K
+
+	c.mu.RLock()
+	now := c.clock.Now().UnixMilli()
+	for _, key := range keys {
+		value, remaining, ok, isStale := c.lookupLocked(key, now)
+		c.recordLookup(ok)
+		if !ok {
+			continue
+		}
+		found[key] = value
+		if withTTL {
+			ttls[key] = remaining
+		}
+		if isStale {
+			stale = append(stale, key)
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, key := range stale {
+		c.refresh(key)
+	}
+	return found, ttls
+}
+
+// SetIfAbsent stores value only if key has no live entry, and reports whether
+// it did. An expired entry counts as absent.
+func (c *TTLCache[K, V]) SetIfAbsent(key K, value V, ttl ...time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return false
+	}
+
+	now := c.clock.Now().UnixMilli()
+	if item, ok := c.cache[key]; ok && now < item.ExpiresAt {
+		return false
+	}
+	c.storeLocked(key, value, now, now+c.effectiveTTL(ttl).Milliseconds())
+	return true
+}
+
+// CompareAndSwap stores new only if key has a live entry equal to old, and
+// reports whether it did. The swapped entry gets the optional ttl or the
+// cache's default TTL, as with Set. As with sync.Map, V must hold comparable
+// values or CompareAndSwap panics.
+func (c *TTLCache[K, V]) CompareAndSwap(key K, old, new V, ttl ...time.Duration) bool {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return false
+	}
+
+	now := c.clock.Now().UnixMilli()
+	item, ok := c.cache[key]
+	if !ok || now >= item.ExpiresAt || any(item.Value) != any(old) {
+		return false
+	}
+	c.storeLocked(key, new, now, now+c.effectiveTTL(ttl).Milliseconds())
+	return true
+}
@@ -0,0 +1,137 @@
+package fxcache
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLenKeysAndHasSkipExpiredEntries(t *testing.T) {
+	cache, clock := newTestCache(t)
+
+	cache.SetMany(map[string]float64{"USD/THB": 36.5, "EUR/USD": 1.08})
+	cache.Set("AUD/USD", 0.66, 50*time.Millisecond)
+	clock.Advance(50 * time.Millisecond)
+
+	if got := cache.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+	keys := cache.Keys()
+	slices.Sort(keys)
+	if want := []string{"EUR/USD", "USD/THB"}; !slices.Equal(keys, want) {
+		t.Errorf("Keys() = %v, want %v", keys, want)
+	}
+	if cache.Has("AUD/USD") || !cache.Has("USD/THB") {
+		t.Errorf("Has(AUD/USD) = %v, Has(USD/THB) = %v, want false, true", cache.Has("AUD/USD"), cache.Has("USD/THB"))
+	}
+
+	got := cache.GetMany([]string{"USD/THB", "AUD/USD", "GBP/USD"})
+	if len(got) != 1 || got["USD/THB"] != 36.5 {
+		t.Errorf("GetMany = %v, want map[USD/THB:36.5]", got)
+	}
+	values, ttls := cache.GetManyWithExpiry([]string{"USD/THB", "AUD/USD"})
+	if len(values) != 1 || values["USD/THB"] != 36.5 || len(ttls) != 1 || ttls["USD/THB"] != time.Hour-50*time.Millisecond {
+		t.Errorf("GetManyWithExpiry = %v, %v, want USD/THB at 36.5 with 59m59.95s left", values, ttls)
+	}
+
+	var visited int
+	cache.Range(func(key string, value float64) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("Range visited %d entries after returning false, want 1", visited)
+	}
+
+	cache.Clear()
+	if got := cache.Len(); got != 0 || stored(cache, "AUD/USD") {
+		t.Errorf("after Clear, Len() = %d and AUD/USD stored = %v", got, stored(cache, "AUD/USD"))
+	}
+}
+
+func TestTouchExtendsTTL(t *testing.T) {
+	cache, clock := newTestCache(t)
+
+	cache.Set("JPY/THB", 0.23, 100*time.Millisecond)
+	clock.Advance(80 * time.Millisecond)
+	if !cache.Touch("JPY/THB", 100*time.Millisecond) {
+		t.Fatal("Touch on live key returned false")
+	}
+	clock.Advance(80 * time.Millisecond)
+
+	if val, ttl, ok := cache.GetWithExpiry("JPY/THB"); !ok || val != 0.23 || ttl != 20*time.Millisecond {
+		t.Fatalf("GetWithExpiry after Touch = (%v, %v, %v), want (0.23, 20ms, true)", val, ttl, ok)
+	}
+	if cache.Touch("GBP/USD") {
+		t.Fatal("Touch on missing key returned true")
+	}
+}
+
+func TestTouchCountsForLRU(t *testing.T) {
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:     time.Hour,
+		MaxEntries:     2,
+		EvictionPolicy: EvictLRU,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	cache.Set("USD/THB", 36.5)
+	cache.Set("EUR/USD", 1.08)
+	cache.Touch("USD/THB")
+	cache.Set("GBP/USD", 1.27)
+	if !cache.Has("USD/THB") || cache.Has("EUR/USD") {
+		t.Fatal("the touched USD/THB was evicted instead of the least recently used EUR/USD")
+	}
+}
+
+func TestSetUntil(t *testing.T) {
+	cache, clock := newTestCache(t)
+
+	if !cache.SetUntil("USD/THB", 36.5, clock.Now().Add(1500*time.Millisecond)) {
+		t.Fatal("SetUntil with a future deadline returned false")
+	}
+	if _, ttl, ok := cache.GetWithExpiry("USD/THB"); !ok || ttl != 1500*time.Millisecond {
+		t.Fatalf("GetWithExpiry after SetUntil = (%v, %v), want 1.5s", ttl, ok)
+	}
+	if cache.SetUntil("EUR/USD", 1.08, clock.Now()) {
+		t.Fatal("SetUntil with a past deadline returned true")
+	}
+	if cache.Has("EUR/USD") {
+		t.Fatal("SetUntil stored an already expired entry")
+	}
+	if cache.DefaultTTL() != time.Hour {
+		t.Fatalf("DefaultTTL = %v, want 1h", cache.DefaultTTL())
+	}
+}
+
+func TestSetIfAbsentAndCompareAndSwap(t *testing.T) {
+	cache, clock := newTestCache(t)
+
+	if !cache.SetIfAbsent("USD/THB", 36.5, 100*time.Millisecond) {
+		t.Fatal("SetIfAbsent on empty cache returned false")
+	}
+	if cache.SetIfAbsent("USD/THB", 99) {
+		t.Fatal("SetIfAbsent on live key returned true")
+	}
+
+	if cache.CompareAndSwap("USD/THB", 36.4, 36.6) {
+		t.Fatal("CompareAndSwap with wrong old value returned true")
+	}
+	if !cache.CompareAndSwap("USD/THB", 36.5, 36.6, 100*time.Millisecond) {
+		t.Fatal("CompareAndSwap with matching old value returned false")
+	}
+	if val, _ := cache.Get("USD/THB"); val != 36.6 {
+		t.Fatalf("Get after CompareAndSwap = %v, want 36.6", val)
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	if cache.CompareAndSwap("USD/THB", 36.6, 36.7) {
+		t.Fatal("CompareAndSwap on expired key returned true")
+	}
+	if !cache.SetIfAbsent("USD/THB", 36.8) {
+		t.Fatal("SetIfAbsent on expired key returned false")
+	}
+}
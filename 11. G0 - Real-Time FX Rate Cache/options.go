@@ -0,0 +1,144 @@
+package fxcache
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Option configures a cache created by New. It is generic so that the
+// loader and the callbacks are checked against the cache's K and V at
+// compile time; options without a K or V argument must be instantiated
+// explicitly, as in WithTTL[string, float64].
+type Option[K comparable, V any] func(*options[K, V])
+
+// options collects the settings of New. index is the position of the option
+// being applied, and err the first misuse of an option.
+type options[K comparable, V any] struct {
+	index     int
+	err       error
+	cfg       Config
+	loader    LoaderFunc[K, V]
+	onEvicted []EvictionFunc[K, V]
+	onSet     []SetFunc[K, V]
+	// metricsMux, if set, serves the cache's stats at metricsPattern.
+	metricsMux     *http.ServeMux
+	metricsPattern string
+}
+
+// WithConfig starts from cfg, for the settings that have no option of their
+// own (snapshots, the write-ahead log, soft TTLs and so on). It replaces the
+// whole Config, so it must be the first option; New returns an error
+// otherwise. Options given after it override its fields.
+func WithConfig[K comparable, V any](cfg Config) Option[K, V] {
+	return func(o *options[K, V]) {
+		if o.index > 0 && o.err == nil {
+			o.err = errors.New("WithConfig must be the first option")
+		}
+		o.cfg = cfg
+	}
+}
+
+// WithTTL sets the lifetime of entries stored without an explicit TTL. It
+// defaults to DefaultCacheTTL.
+func WithTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
+	return func(o *options[K, V]) { o.cfg.DefaultTTL = ttl }
+}
+
+// WithJanitorInterval sets the minimum time between two janitor sweeps. It
+// defaults to DefaultJanitorInterval and must not exceed the TTL.
+func WithJanitorInterval[K comparable, V any](interval time.Duration) Option[K, V] {
+	return func(o *options[K, V]) { o.cfg.JanitorInterval = interval }
+}
+
+// WithMaxEntries caps the number of entries, evicting by policy when the cap
+// is reached.
+func WithMaxEntries[K comparable, V any](n int, policy EvictionPolicy) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.cfg.MaxEntries = n
+		o.cfg.EvictionPolicy = policy
+	}
+}
+
+// WithMaxBytes caps the estimated memory used by entries, as measured by
+// sizer, or EstimateSize if sizer is nil.
+func WithMaxBytes[K comparable, V any](n int64, sizer func(key, value any) int64) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.cfg.MaxBytes = n
+		o.cfg.Sizer = sizer
+	}
+}
+
+// WithClock sets the time source. It defaults to SystemClock.
+func WithClock[K comparable, V any](clock Clock) Option[K, V] {
+	return func(o *options[K, V]) { o.cfg.Clock = clock }
+}
+
+// WithLoader registers loader as if by SetLoader.
+func WithLoader[K comparable, V any](loader LoaderFunc[K, V]) Option[K, V] {
+	return func(o *options[K, V]) { o.loader = loader }
+}
+
+// WithOnEvicted registers fn as if by OnEvicted. It may be given several
+// times.
+func WithOnEvicted[K comparable, V any](fn EvictionFunc[K, V]) Option[K, V] {
+	return func(o *options[K, V]) { o.onEvicted = append(o.onEvicted, fn) }
+}
+
+// WithOnSet registers fn as if by OnSet. It may be given several times.
+func WithOnSet[K comparable, V any](fn SetFunc[K, V]) Option[K, V] {
+	return func(o *options[K, V]) { o.onSet = append(o.onSet, fn) }
+}
+
+// WithMetrics serves the cache's stats on mux at pattern (e.g. "GET
+// /metrics"), as NewMetricsHandler does.
+func WithMetrics[K comparable, V any](mux *http.ServeMux, pattern string) Option[K, V] {
+	return func(o *options[K, V]) {
+		o.metricsMux = mux
+		o.metricsPattern = pattern
+	}
+}
+
+// New creates a cache configured by opts. K and V are inferred from the
+// options. Invalid settings are reported with errors that wrap one of
+// ErrNegativeTTL, ErrJanitorTooSlow, ErrNegativeInterval, ErrNegativeLimit,
+// ErrUnknownEvictionPolicy, ErrNoSnapshotPath and ErrUnknownWALSync, which
+// can be matched with errors.Is. A WithConfig that is not the first option
+// and a WithMetrics without a pattern are reported with plain errors.
+//
+//	cache, err := fxcache.New(
+//		fxcache.WithTTL[string, float64](30*time.Second),
+//		fxcache.WithMaxEntries[string, float64](10_000, fxcache.EvictLRU),
+//	)
+func New[K comparable, V any](opts ...Option[K, V]) (*TTLCache[K, V], error) {
+	var o options[K, V]
+	for i, opt := range opts {
+		o.index = i
+		opt(&o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+	if o.metricsMux != nil && o.metricsPattern == "" {
+		return nil, fmt.Errorf("metrics pattern must not be empty")
+	}
+
+	cache, err := NewTTLCacheWithConfig[K, V](o.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if o.loader != nil {
+		cache.SetLoader(o.loader)
+	}
+	for _, fn := range o.onEvicted {
+		cache.OnEvicted(fn)
+	}
+	for _, fn := range o.onSet {
+		cache.OnSet(fn)
+	}
+	if o.metricsMux != nil {
+		o.metricsMux.Handle(o.metricsPattern, NewMetricsHandler(cache))
+	}
+	return cache, nil
+}
@@ -0,0 +1,115 @@
+package fxcache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAppliesOptions(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	var evicted, set []string
+	mux := http.NewServeMux()
+	cache, err := New(
+		WithTTL[string, float64](time.Minute),
+		WithJanitorInterval[string, float64](time.Second),
+		WithMaxEntries[string, float64](2, EvictLRU),
+		WithClock[string, float64](clock),
+		WithLoader(func(ctx context.Context, key string) (float64, error) { return 36.5, nil }),
+		WithOnEvicted(func(key string, _ float64, reason EvictionReason) { evicted = append(evicted, key) }),
+		WithOnSet(func(key string, _ float64, _ time.Time) { set = append(set, key) }),
+		WithMetrics[string, float64](mux, "GET /metrics"),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	if got := cache.DefaultTTL(); got != time.Minute {
+		t.Errorf("DefaultTTL() = %v, want 1m", got)
+	}
+	if val, err := cache.GetOrLoad(context.Background(), "USD/THB", nil); err != nil || val != 36.5 {
+		t.Errorf("GetOrLoad with the loader option = (%v, %v), want (36.5, nil)", val, err)
+	}
+	if _, ttl, _ := cache.GetWithExpiry("USD/THB"); ttl != time.Minute {
+		t.Errorf("TTL = %v, want 1m", ttl)
+	}
+	cache.Set("EUR/USD", 1.08)
+	cache.Set("GBP/USD", 1.27)
+	if len(evicted) != 1 || evicted[0] != "USD/THB" {
+		t.Errorf("evicted %v, want [USD/THB] by the two-entry cap", evicted)
+	}
+	if len(set) != 3 {
+		t.Errorf("OnSet saw %v, want three sets", set)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "ttlcache_sets_total 3") {
+		t.Errorf("metrics do not report the sets:\n%s", rec.Body)
+	}
+}
+
+func TestNewOptionOrder(t *testing.T) {
+	// Options after WithConfig override it.
+	cache, err := New(
+		WithConfig[string, float64](Config{DefaultTTL: time.Minute, JanitorInterval: time.Minute}),
+		WithTTL[string, float64](time.Hour),
+		WithJanitorInterval[string, float64](time.Second),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer cache.StopJanitor()
+	if got := cache.DefaultTTL(); got != time.Hour {
+		t.Errorf("DefaultTTL() = %v, want the 1h of WithTTL", got)
+	}
+
+	// WithConfig would drop the settings of the options before it.
+	_, err = New(
+		WithTTL[string, float64](time.Hour),
+		WithConfig[string, float64](Config{JanitorInterval: time.Second}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "WithConfig must be the first option") {
+		t.Errorf("New with WithConfig second: err = %v, want it rejected", err)
+	}
+}
+
+func TestNewValidationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option[string, float64]
+		want error
+	}{
+		{"negative TTL", []Option[string, float64]{WithTTL[string, float64](-time.Second)}, ErrNegativeTTL},
+		{"negative soft TTL", []Option[string, float64]{WithConfig[string, float64](Config{SoftTTL: -time.Second})}, ErrNegativeTTL},
+		{"slow janitor", []Option[string, float64]{WithTTL[string, float64](time.Second), WithJanitorInterval[string, float64](time.Minute)}, ErrJanitorTooSlow},
+		{"negative janitor interval", []Option[string, float64]{WithJanitorInterval[string, float64](-time.Second)}, ErrNegativeInterval},
+		{"negative max entries", []Option[string, float64]{WithMaxEntries[string, float64](-1, EvictLRU)}, ErrNegativeLimit},
+		{"negative max bytes", []Option[string, float64]{WithMaxBytes[string, float64](-1, nil)}, ErrNegativeLimit},
+		{"unknown eviction policy", []Option[string, float64]{WithMaxEntries[string, float64](10, EvictionPolicy(99))}, ErrUnknownEvictionPolicy},
+		{"negative refresh-ahead", []Option[string, float64]{WithConfig[string, float64](Config{RefreshAhead: -time.Second})}, ErrNegativeInterval},
+		{"negative snapshot interval", []Option[string, float64]{WithConfig[string, float64](Config{SnapshotInterval: -time.Second})}, ErrNegativeInterval},
+		{"snapshot interval without path", []Option[string, float64]{WithConfig[string, float64](Config{SnapshotInterval: time.Second})}, ErrNoSnapshotPath},
+		{"unknown WAL sync policy", []Option[string, float64]{WithConfig[string, float64](Config{WALSync: WALSyncPolicy(99)})}, ErrUnknownWALSync},
+		{"negative WAL sync interval", []Option[string, float64]{WithConfig[string, float64](Config{WALSyncInterval: -time.Second})}, ErrNegativeInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts...)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("New: err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// The old constructor reports the same errors, with the values.
+	_, err := NewTTLCache[string, float64](time.Second, time.Minute)
+	if !errors.Is(err, ErrJanitorTooSlow) || !strings.Contains(err.Error(), "janitor interval 1m0s, default TTL 1s") {
+		t.Errorf("NewTTLCache: err = %v, want ErrJanitorTooSlow with the durations", err)
+	}
+}
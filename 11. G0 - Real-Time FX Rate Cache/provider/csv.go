@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+)
+
+// CSVProvider reads rates from a CSV file with one "pair,rate" row per pair,
+// e.g. "USD/THB,36.5". An optional "pair,rate" header row and lines starting
+// with '#' are skipped. The file is read again on every call, so another
+// process can update it by rewriting it.
+type CSVProvider struct {
+	path     string
+	interval time.Duration
+}
+
+// NewCSVProvider returns a provider for the file at path, which is expected
+// to be rewritten every interval.
+func NewCSVProvider(path string, interval time.Duration) *CSVProvider {
+	return &CSVProvider{path: path, interval: interval}
+}
+
+// Name returns "csv:" followed by the path.
+func (p *CSVProvider) Name() string {
+	return "csv:" + p.path
+}
+
+// UpdateInterval returns the interval given to NewCSVProvider.
+func (p *CSVProvider) UpdateInterval() time.Duration {
+	return p.interval
+}
+
+// Rates reads the file and returns the rates of the requested pairs.
+func (p *CSVProvider) Rates(ctx context.Context, pairs []fxcache.CurrencyPair) (map[fxcache.CurrencyPair]float64, error) {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	all, err := ReadCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.path, err)
+	}
+	rates := make(map[fxcache.CurrencyPair]float64, len(pairs))
+	for _, pair := range pairs {
+		if rate, ok := all[pair]; ok {
+			rates[pair] = rate
+		}
+	}
+	return rates, nil
+}
+
+// ReadCSV parses rates in the format read by CSVProvider. Rates must be
+// positive numbers.
+func ReadCSV(r io.Reader) (map[fxcache.CurrencyPair]float64, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+
+	rates := make(map[fxcache.CurrencyPair]float64)
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first && strings.EqualFold(record[0], "pair") {
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		pair, err := fxcache.ParseCurrencyPair(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil || !(rate > 0) || math.IsInf(rate, 0) {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[1])
+		}
+		rates[pair] = rate
+	}
+}
@@ -0,0 +1,66 @@
+package provider_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+	"g0-real-time-fx-rate-cache/provider"
+)
+
+func TestCSVProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("pair,rate\n# morning fixing\nUSD/THB, 36.5\neur/usd,1.08\n")
+
+	cache, _ := newCache(t)
+	poller, err := provider.NewPoller(cache, provider.Config{
+		Provider: provider.NewCSVProvider(path, time.Minute),
+		Pairs:    pairs(t, "USD/THB", "EUR/USD"),
+	})
+	if err != nil {
+		t.Fatalf("NewPoller: %v", err)
+	}
+	if err := poller.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if rate, ttl, ok := cache.GetWithExpiry("EUR/USD"); !ok || rate != 1.08 || ttl != 2*time.Minute {
+		t.Fatalf("EUR/USD = %v, %v, %v, want 1.08 for 2m", rate, ttl, ok)
+	}
+
+	// The file is read again on every poll.
+	write("USD/THB,36.6\n")
+	err = poller.Poll(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "no rate for EUR/USD") {
+		t.Fatalf("Poll = %v, want EUR/USD missing", err)
+	}
+	if rate, _ := cache.Get("USD/THB"); rate != 36.6 {
+		t.Fatalf("USD/THB = %v, want 36.6", rate)
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	for _, content := range []string{
+		"USD/THB,36.5\nUSDTHB,1\n",
+		"USD/THB,abc\n",
+		"USD/THB,-1\n",
+		"USD/THB,36.5,extra\n",
+	} {
+		if _, err := provider.ReadCSV(strings.NewReader(content)); err == nil {
+			t.Errorf("ReadCSV(%q) succeeded, want an error", content)
+		}
+	}
+	rates, err := provider.ReadCSV(strings.NewReader("JPY/THB,0.2433\n"))
+	if err != nil || rates[fxcache.CurrencyPair{Base: "JPY", Quote: "THB"}] != 0.2433 {
+		t.Fatalf("ReadCSV = %v, %v, want JPY/THB 0.2433", rates, err)
+	}
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+)
+
+// HTTPProvider fetches rates from an HTTP/JSON service that answers
+// GET {baseURL}/rates?pairs=USD/THB,EUR/USD with
+//
+//	{"rates": [{"pair": "USD/THB", "rate": 36.5}], "missing": ["EUR/USD"]}
+//
+// which is the bulk endpoint of cmd/fxcache, so one fxcache service can feed
+// another.
+type HTTPProvider struct {
+	baseURL  string
+	interval time.Duration
+	// Client makes the requests. It defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// NewHTTPProvider returns a provider for the service at baseURL, which
+// updates its rates every interval.
+func NewHTTPProvider(baseURL string, interval time.Duration) *HTTPProvider {
+	return &HTTPProvider{baseURL: strings.TrimSuffix(baseURL, "/"), interval: interval}
+}
+
+// Name returns the base URL.
+func (p *HTTPProvider) Name() string {
+	return p.baseURL
+}
+
+// UpdateInterval returns the interval given to NewHTTPProvider.
+func (p *HTTPProvider) UpdateInterval() time.Duration {
+	return p.interval
+}
+
+// bulkResponse is the body returned by the rates endpoint.
+type bulkResponse struct {
+	Rates []struct {
+		Pair string  `json:"pair"`
+		Rate float64 `json:"rate"`
+	} `json:"rates"`
+	Error string `json:"error"`
+}
+
+// Rates requests every pair in one call. Like ReadCSV, it rejects a response
+// with a rate that is not finite and positive.
+func (p *HTTPProvider) Rates(ctx context.Context, pairs []fxcache.CurrencyPair) (map[fxcache.CurrencyPair]float64, error) {
+	names := make([]string, len(pairs))
+	for i, pair := range pairs {
+		names[i] = pair.String()
+	}
+	u := p.baseURL + "/rates?" + url.Values{"pairs": {strings.Join(names, ",")}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if body.Error != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, body.Error)
+		}
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	rates := make(map[fxcache.CurrencyPair]float64, len(body.Rates))
+	for _, r := range body.Rates {
+		pair, err := fxcache.ParseCurrencyPair(r.Pair)
+		if err != nil {
+			return nil, err
+		}
+		if !(r.Rate > 0) || math.IsInf(r.Rate, 0) {
+			return nil, fmt.Errorf("invalid rate %v for %s", r.Rate, pair)
+		}
+		rates[pair] = r.Rate
+	}
+	return rates, nil
+}
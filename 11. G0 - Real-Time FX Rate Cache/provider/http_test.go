@@ -0,0 +1,39 @@
+package provider_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"g0-real-time-fx-rate-cache/provider"
+	"g0-real-time-fx-rate-cache/provider/providertest"
+)
+
+func TestHTTPProviderRejectsInvalidRates(t *testing.T) {
+	srv := providertest.NewServer(map[string]float64{"USD/THB": 36.5})
+	defer srv.Close()
+	p := provider.NewHTTPProvider(srv.URL, time.Minute)
+
+	for _, rate := range []float64{0, -36.5} {
+		srv.SetRate("EUR/USD", rate)
+		_, err := p.Rates(context.Background(), pairs(t, "USD/THB", "EUR/USD"))
+		if err == nil || !strings.Contains(err.Error(), "invalid rate") {
+			t.Errorf("Rates with EUR/USD at %v: err = %v, want an invalid rate", rate, err)
+		}
+	}
+
+	// The poller keeps the last good rates.
+	cache, _ := newCache(t)
+	cache.Set("EUR/USD", 1.08)
+	poller, err := provider.NewPoller(cache, provider.Config{Provider: p, Pairs: pairs(t, "EUR/USD")})
+	if err != nil {
+		t.Fatalf("NewPoller: %v", err)
+	}
+	if err := poller.Poll(context.Background()); err == nil {
+		t.Fatal("Poll with an invalid rate succeeded")
+	}
+	if rate, _ := cache.Get("EUR/USD"); rate != 1.08 {
+		t.Fatalf("EUR/USD = %v, want the last good 1.08", rate)
+	}
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+)
+
+// DefaultTTLFactor is the number of update intervals a polled rate lives
+// when Config.TTL is zero, so that one failed poll does not expire it.
+const DefaultTTLFactor = 2
+
+// Config holds the settings used by NewPoller. Zero values fall back to the
+// defaults.
+type Config struct {
+	// Provider is the source of the rates. It is required.
+	Provider RateProvider
+	// Pairs are the pairs to poll. At least one is required.
+	Pairs []fxcache.CurrencyPair
+	// Interval is the time between polls. It defaults to the provider's
+	// UpdateInterval.
+	Interval time.Duration
+	// TTL is the lifetime of the stored rates. It defaults to
+	// DefaultTTLFactor × Interval.
+	TTL time.Duration
+	// Timeout bounds one call to the provider. It defaults to Interval.
+	Timeout time.Duration
+	// Clock schedules the polls. It defaults to fxcache.SystemClock.
+	Clock fxcache.Clock
+	// OnError receives the errors of polls made by Run. It defaults to
+	// logging them.
+	OnError func(error)
+	// Writer receives the polled rates, e.g. a cluster.Node that replicates
+	// them. It defaults to the cache.
+	Writer fxcache.RateWriter
+}
+
+// Poller copies rates from a RateProvider into a cache.
+type Poller struct {
+	writer   fxcache.RateWriter
+	provider RateProvider
+	pairs    []fxcache.CurrencyPair
+	interval time.Duration
+	ttl      time.Duration
+	timeout  time.Duration
+	clock    fxcache.Clock
+	onError  func(error)
+}
+
+// NewPoller returns a Poller that writes into cache, or through
+// Config.Writer. It returns an error if cfg is incomplete or has negative
+// durations.
+func NewPoller(cache *fxcache.FXRateCache, cfg Config) (*Poller, error) {
+	if cfg.Provider == nil {
+		return nil, errors.New("poller needs a provider")
+	}
+	if len(cfg.Pairs) == 0 {
+		return nil, errors.New("poller needs at least one pair")
+	}
+	if cfg.Interval < 0 || cfg.TTL < 0 || cfg.Timeout < 0 {
+		return nil, errors.New("poller durations must not be negative")
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = cfg.Provider.UpdateInterval()
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("provider %s has no update interval; set Config.Interval", cfg.Provider.Name())
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = DefaultTTLFactor * cfg.Interval
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = cfg.Interval
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = fxcache.SystemClock{}
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(err error) { log.Printf("provider: %v", err) }
+	}
+	if cfg.Writer == nil {
+		cfg.Writer = cache
+	}
+	return &Poller{
+		writer:   cfg.Writer,
+		provider: cfg.Provider,
+		pairs:    cfg.Pairs,
+		interval: cfg.Interval,
+		ttl:      cfg.TTL,
+		timeout:  cfg.Timeout,
+		clock:    cfg.Clock,
+		onError:  cfg.OnError,
+	}, nil
+}
+
+// TTL returns the lifetime given to the stored rates.
+func (p *Poller) TTL() time.Duration {
+	return p.ttl
+}
+
+// Poll fetches the pairs once and stores the rates that were returned. It
+// returns an error if the provider failed or left pairs out; the rates it did
+// return are stored either way.
+func (p *Poller) Poll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	rates, err := p.provider.Rates(ctx, p.pairs)
+	if err != nil {
+		return fmt.Errorf("poll %s: %w", p.provider.Name(), err)
+	}
+
+	entries := make(map[string]float64, len(rates))
+	var missing []string
+	for _, pair := range p.pairs {
+		if rate, ok := rates[pair]; ok {
+			entries[pair.String()] = rate
+		} else {
+			missing = append(missing, pair.String())
+		}
+	}
+	p.writer.SetMany(entries, p.ttl)
+	if len(missing) > 0 {
+		return fmt.Errorf("poll %s: no rate for %s", p.provider.Name(), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Run polls immediately and then every interval until ctx is done, passing
+// poll errors to Config.OnError. It returns ctx.Err().
+func (p *Poller) Run(ctx context.Context) error {
+	var timer fxcache.Timer
+	for {
+		if err := p.Poll(ctx); err != nil && ctx.Err() == nil {
+			p.onError(err)
+		}
+		// The next poll is scheduled after this one finished, so a slow
+		// provider is never polled concurrently.
+		if timer == nil {
+			timer = p.clock.NewTimer(p.interval)
+			defer timer.Stop()
+		} else {
+			timer.Reset(p.interval)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package provider_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+	"g0-real-time-fx-rate-cache/provider"
+	"g0-real-time-fx-rate-cache/provider/providertest"
+)
+
+func pairs(t *testing.T, names ...string) []fxcache.CurrencyPair {
+	t.Helper()
+	var out []fxcache.CurrencyPair
+	for _, name := range names {
+		pair, err := fxcache.ParseCurrencyPair(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, pair)
+	}
+	return out
+}
+
+func newCache(t *testing.T) (*fxcache.FXRateCache, *fxcache.ManualClock) {
+	t.Helper()
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	cache, err := fxcache.NewTTLCacheWithConfig[string, float64](fxcache.Config{
+		DefaultTTL:      time.Hour,
+		JanitorInterval: time.Hour,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	t.Cleanup(cache.StopJanitor)
+	return cache, clock
+}
+
+func TestPollerRun(t *testing.T) {
+	srv := providertest.NewServer(map[string]float64{"USD/THB": 36.5, "EUR/USD": 1.08})
+	defer srv.Close()
+	cache, cacheClock := newCache(t)
+
+	pollClock := fxcache.NewManualClock(time.Unix(0, 0))
+	var mu sync.Mutex
+	var errs []error
+	poller, err := provider.NewPoller(cache, provider.Config{
+		Provider: provider.NewHTTPProvider(srv.URL, 5*time.Second),
+		Pairs:    pairs(t, "USD/THB", "EUR/USD", "GBP/USD"),
+		Clock:    pollClock,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPoller: %v", err)
+	}
+	if poller.TTL() != 10*time.Second {
+		t.Fatalf("TTL = %v, want twice the provider's 5s update interval", poller.TTL())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- poller.Run(ctx) }()
+
+	// Run polls at once and then arms its timer.
+	pollClock.BlockUntil(1)
+	if rate, ttl, ok := cache.GetWithExpiry("USD/THB"); !ok || rate != 36.5 || ttl != 10*time.Second {
+		t.Fatalf("USD/THB = %v, %v, %v after the first poll, want 36.5 for 10s", rate, ttl, ok)
+	}
+	mu.Lock()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "no rate for GBP/USD") {
+		t.Fatalf("errors = %v, want GBP/USD missing", errs)
+	}
+	mu.Unlock()
+
+	srv.SetRate("USD/THB", 36.6)
+	pollClock.Advance(5 * time.Second)
+	pollClock.BlockUntil(1)
+	if rate, _ := cache.Get("USD/THB"); rate != 36.6 {
+		t.Fatalf("USD/THB = %v after the second poll, want 36.6", rate)
+	}
+
+	// A failing provider leaves the last rates in place until they expire.
+	srv.SetFailing(true)
+	cacheClock.Advance(5 * time.Second)
+	pollClock.Advance(5 * time.Second)
+	pollClock.BlockUntil(1)
+	if _, ok := cache.Get("EUR/USD"); !ok {
+		t.Fatal("EUR/USD expired after one failed poll")
+	}
+	cacheClock.Advance(5 * time.Second)
+	if _, ok := cache.Get("EUR/USD"); ok {
+		t.Fatal("EUR/USD still cached after its TTL")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+	if n := srv.Requests(); n != 3 {
+		t.Fatalf("provider got %d requests, want 3", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 3 || !strings.Contains(errs[2].Error(), "503") {
+		t.Fatalf("errors = %v, want the 503 reported", errs)
+	}
+}
+
+// recordingWriter is a RateWriter that stands in for a cluster.Node.
+type recordingWriter struct {
+	*fxcache.FXRateCache
+	batches []map[string]float64
+}
+
+func (w *recordingWriter) SetMany(rates map[string]float64, ttl ...time.Duration) {
+	w.batches = append(w.batches, rates)
+	w.FXRateCache.SetMany(rates, ttl...)
+}
+
+func TestPollerWritesThroughWriter(t *testing.T) {
+	srv := providertest.NewServer(map[string]float64{"USD/THB": 36.5})
+	defer srv.Close()
+	cache, _ := newCache(t)
+	writer := &recordingWriter{FXRateCache: cache}
+	poller, err := provider.NewPoller(cache, provider.Config{
+		Provider: provider.NewHTTPProvider(srv.URL, 5*time.Second),
+		Pairs:    pairs(t, "USD/THB"),
+		Writer:   writer,
+	})
+	if err != nil {
+		t.Fatalf("NewPoller: %v", err)
+	}
+	if err := poller.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	if len(writer.batches) != 1 || writer.batches[0]["USD/THB"] != 36.5 {
+		t.Fatalf("writer got %v, want one batch with USD/THB 36.5", writer.batches)
+	}
+}
+
+func TestNewPollerValidates(t *testing.T) {
+	cache, _ := newCache(t)
+	p := provider.NewCSVProvider("rates.csv", 0)
+	for _, cfg := range []provider.Config{
+		{Pairs: pairs(t, "USD/THB"), Interval: time.Second},
+		{Provider: p, Interval: time.Second},
+		{Provider: p, Pairs: pairs(t, "USD/THB")},
+		{Provider: p, Pairs: pairs(t, "USD/THB"), Interval: -time.Second},
+	} {
+		if _, err := provider.NewPoller(cache, cfg); err == nil {
+			t.Errorf("NewPoller(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package provider fetches FX rates from external sources and keeps an
+// fxcache.FXRateCache up to date with them.
+//
+// A RateProvider is a source of rates: CSVProvider reads a file and
+// HTTPProvider calls an HTTP/JSON service such as cmd/fxcache. A Poller asks
+// a provider for a fixed set of pairs at the provider's update frequency and
+// stores the answers with a TTL derived from it, so rates expire if the
+// provider stops updating them. Package providertest has a fake HTTP
+// provider for running the pipeline offline.
+package provider
+
+import (
+	"context"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+)
+
+// RateProvider is a source of FX rates.
+type RateProvider interface {
+	// Name identifies the provider in errors and logs.
+	Name() string
+	// UpdateInterval is how often the provider publishes new rates. The
+	// Poller polls at this frequency by default and derives the TTL of the
+	// rates from it.
+	UpdateInterval() time.Duration
+	// Rates returns the current rate of each of pairs that the provider
+	// knows. Pairs it has no rate for are left out of the result.
+	Rates(ctx context.Context, pairs []fxcache.CurrencyPair) (map[fxcache.CurrencyPair]float64, error)
+}
@@ -0,0 +1,96 @@
+// Package providertest provides a fake HTTP rate provider for tests and
+// offline demos. It speaks the protocol read by provider.HTTPProvider.
+package providertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Server is an httptest.Server that serves a mutable set of rates.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	rates    map[string]float64
+	failing  bool
+	requests int
+}
+
+// rate and response mirror the JSON of cmd/fxcache's bulk endpoint.
+type rate struct {
+	Pair string  `json:"pair"`
+	Rate float64 `json:"rate"`
+}
+
+type response struct {
+	Rates   []rate   `json:"rates"`
+	Missing []string `json:"missing"`
+}
+
+// NewServer starts a server with the given rates, keyed like "USD/THB". The
+// caller must call Close when done.
+func NewServer(rates map[string]float64) *Server {
+	s := &Server{rates: make(map[string]float64, len(rates))}
+	for pair, r := range rates {
+		s.rates[pair] = r
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rates", s.serveRates)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// SetRate changes the rate of pair.
+func (s *Server) SetRate(pair string, r float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates[pair] = r
+}
+
+// DeleteRate removes pair, which is then reported as missing.
+func (s *Server) DeleteRate(pair string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rates, pair)
+}
+
+// SetFailing makes the server answer 503 Service Unavailable while failing is
+// true.
+func (s *Server) SetFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+// Requests returns the number of requests served so far.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) serveRates(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	w.Header().Set("Content-Type", "application/json")
+	if s.failing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "provider unavailable"})
+		return
+	}
+	resp := response{Rates: []rate{}, Missing: []string{}}
+	for _, pair := range strings.Split(r.URL.Query().Get("pairs"), ",") {
+		if v, ok := s.rates[pair]; ok {
+			resp.Rates = append(resp.Rates, rate{Pair: pair, Rate: v})
+		} else if pair != "" {
+			resp.Missing = append(resp.Missing, pair)
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
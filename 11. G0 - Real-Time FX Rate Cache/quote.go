@@ -0,0 +1,233 @@
+package fxcache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Side selects one price of a two-sided quote.
+type Side int
+
+const (
+	// SideMid is the average of the bid and the ask.
+	SideMid Side = iota
+	// SideBid is the price at which the quoting dealer buys the base currency.
+	SideBid
+	// SideAsk is the price at which the quoting dealer sells the base currency.
+	SideAsk
+)
+
+// String returns the side name.
+func (s Side) String() string {
+	switch s {
+	case SideMid:
+		return "mid"
+	case SideBid:
+		return "bid"
+	case SideAsk:
+		return "ask"
+	default:
+		return fmt.Sprintf("Side(%d)", int(s))
+	}
+}
+
+// derivedScale is the number of decimal places kept when a quote is
+// inverted.
+const derivedScale = 12
+
+// Quote is a two-sided price for a currency pair: one unit of the base
+// currency can be sold for Bid or bought for Ask units of the quote currency.
+type Quote struct {
+	Bid Decimal `json:"bid"`
+	Ask Decimal `json:"ask"`
+	// PipDigits is the decimal place of a pip, e.g. 4 for EUR/USD (a pip is
+	// 0.0001) and 2 for USD/JPY.
+	PipDigits int32 `json:"pip_digits"`
+	// Timestamp is when the source published the quote.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewQuote returns a quote from float prices, rounded to a tenth of a pip
+// (pipDigits+1 decimal places), which is how FX prices are usually quoted.
+func NewQuote(bid, ask float64, pipDigits int32, timestamp time.Time) (Quote, error) {
+	b, err := DecimalFromFloat(bid, pipDigits+1)
+	if err != nil {
+		return Quote{}, err
+	}
+	a, err := DecimalFromFloat(ask, pipDigits+1)
+	if err != nil {
+		return Quote{}, err
+	}
+	q := Quote{Bid: b, Ask: a, PipDigits: pipDigits, Timestamp: timestamp}
+	return q, q.Validate()
+}
+
+// DefaultPipDigits returns the market convention for the pip of pair: 2 when
+// it is quoted in yen and 4 otherwise.
+func DefaultPipDigits(pair CurrencyPair) int32 {
+	if pair.Quote == "JPY" {
+		return 2
+	}
+	return 4
+}
+
+// Validate checks that both prices are positive and the bid does not exceed
+// the ask.
+func (q Quote) Validate() error {
+	if q.Bid.Sign() <= 0 || q.Ask.Sign() <= 0 {
+		return fmt.Errorf("quote %s/%s must have positive prices", q.Bid, q.Ask)
+	}
+	if q.Bid.Cmp(q.Ask) > 0 {
+		return fmt.Errorf("quote bid %s is above ask %s", q.Bid, q.Ask)
+	}
+	if q.PipDigits < 0 {
+		return fmt.Errorf("quote pip digits %d must not be negative", q.PipDigits)
+	}
+	return nil
+}
+
+// Mid returns the exact average of the bid and the ask.
+func (q Quote) Mid() Decimal {
+	// (bid + ask) / 2 = (bid + ask) × 5 / 10, which is exact.
+	return q.Bid.Add(q.Ask).Mul(NewDecimal(5, 1))
+}
+
+// Spread returns ask - bid.
+func (q Quote) Spread() Decimal {
+	return q.Ask.Sub(q.Bid)
+}
+
+// SpreadPips returns the spread in pips, e.g. 1.2 for 1.08000/1.08012.
+func (q Quote) SpreadPips() Decimal {
+	return q.Spread().Shift(q.PipDigits)
+}
+
+// Price returns the price of the given side.
+func (q Quote) Price(side Side) Decimal {
+	switch side {
+	case SideBid:
+		return q.Bid
+	case SideAsk:
+		return q.Ask
+	default:
+		return q.Mid()
+	}
+}
+
+// inverse returns the quote of the opposite pair: selling the old quote
+// currency buys at the inverse of the old ask, and buying it costs the
+// inverse of the old bid.
+func (q Quote) inverse() Quote {
+	return Quote{
+		Bid:       NewDecimal(1, 0).Div(q.Ask, derivedScale),
+		Ask:       NewDecimal(1, 0).Div(q.Bid, derivedScale),
+		PipDigits: q.PipDigits,
+		Timestamp: q.Timestamp,
+	}
+}
+
+// crossQuotes returns the quote of A/C from quotes of A/B and B/C. It is as
+// old as the older of the two.
+func crossQuotes(first, second Quote) Quote {
+	q := Quote{
+		Bid:       first.Bid.Mul(second.Bid),
+		Ask:       first.Ask.Mul(second.Ask),
+		PipDigits: first.PipDigits,
+		Timestamp: first.Timestamp,
+	}
+	if second.Timestamp.Before(q.Timestamp) {
+		q.Timestamp = second.Timestamp
+	}
+	return q
+}
+
+// QuoteCache is a TTLCache keyed by currency pair (e.g. "USD/THB") that
+// stores a two-sided quote per pair.
+type QuoteCache = TTLCache[string, Quote]
+
+// NewQuoteCache creates a new QuoteCache. See NewTTLCache for the meaning of
+// the arguments.
+func NewQuoteCache(defaultTTL time.Duration, janitorInterval ...time.Duration) (*QuoteCache, error) {
+	return NewTTLCache[string, Quote](defaultTTL, janitorInterval...)
+}
+
+// DerivedQuote is a quote answered by QuoteBook.
+type DerivedQuote struct {
+	Pair  CurrencyPair
+	Quote Quote
+	// TTL is the shortest remaining TTL of the cached quotes used.
+	TTL    time.Duration
+	Source RateSource
+}
+
+// QuoteBook is the two-sided counterpart of RateBook: it answers quotes for
+// any currency pair from a QuoteCache, and converts amounts with them.
+type QuoteBook struct {
+	cache *QuoteCache
+	pivot Currency
+}
+
+// NewQuoteBook returns a QuoteBook over cache that crosses through pivot, or
+// DefaultPivot if pivot is empty.
+func NewQuoteBook(cache *QuoteCache, pivot Currency) (*QuoteBook, error) {
+	if pivot == "" {
+		pivot = DefaultPivot
+	}
+	if !pivot.Valid() {
+		return nil, fmt.Errorf("unknown pivot currency %q", pivot)
+	}
+	return &QuoteBook{cache: cache, pivot: pivot}, nil
+}
+
+// Set caches the quote of pair after validating it.
+func (b *QuoteBook) Set(pair CurrencyPair, q Quote, ttl ...time.Duration) error {
+	if err := q.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", pair, err)
+	}
+	b.cache.Set(pair.String(), q, ttl...)
+	return nil
+}
+
+// Quote returns the quote of base/quote, derived as in RateBook.Rate. Inverted
+// prices are kept to 12 decimal places and crosses are exact; derived quotes
+// use DefaultPipDigits and the oldest timestamp of their legs. It returns an
+// error wrapping ErrNoRate if no such quotes are cached.
+func (b *QuoteBook) Quote(base, quote Currency) (DerivedQuote, error) {
+	pair, err := NewCurrencyPair(base, quote)
+	if err != nil {
+		return DerivedQuote{}, err
+	}
+
+	q, ttl, source, ok := derive(pair, b.pivot, b.lookup, Quote.inverse, crossQuotes)
+	if !ok {
+		return DerivedQuote{}, fmt.Errorf("%w for %s", ErrNoRate, pair)
+	}
+	if source != RateDirect {
+		q.PipDigits = DefaultPipDigits(pair)
+	}
+	return DerivedQuote{Pair: pair, Quote: q, TTL: ttl, Source: source}, nil
+}
+
+// lookup returns a cached quote, skipping invalid ones, which may have been
+// stored in the cache directly and cannot be inverted.
+func (b *QuoteBook) lookup(key string) (Quote, time.Duration, bool) {
+	q, ttl, ok := b.cache.GetWithExpiry(key)
+	return q, ttl, ok && q.Validate() == nil
+}
+
+// Convert returns amount of from converted to to at the given side of the
+// from/to quote, rounded half away from zero to the minor units of to. For
+// example, selling USD for THB to a dealer uses SideBid of USD/THB.
+func (b *QuoteBook) Convert(amount Decimal, from, to Currency, side Side) (Decimal, error) {
+	if from == to {
+		if !to.Valid() {
+			return Decimal{}, fmt.Errorf("unknown currency %q", to)
+		}
+		return amount.Round(int32(to.MinorUnits())), nil
+	}
+	q, err := b.Quote(from, to)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return amount.Mul(q.Quote.Price(side)).Round(int32(to.MinorUnits())), nil
+}
@@ -0,0 +1,146 @@
+package fxcache
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuote(t *testing.T) {
+	ts := time.Unix(1_700_000_000, 0)
+	q, err := NewQuote(1.08001, 1.080134, 4, ts)
+	if err != nil {
+		t.Fatalf("NewQuote: %v", err)
+	}
+	if q.Bid.String() != "1.08001" || q.Ask.String() != "1.08013" {
+		t.Fatalf("prices = %s/%s, want rounded to a tenth of a pip", q.Bid, q.Ask)
+	}
+	if q.Mid().String() != "1.080070" || q.SpreadPips().String() != "1.2" {
+		t.Fatalf("mid %s, spread %s pips, want 1.080070 and 1.2", q.Mid(), q.SpreadPips())
+	}
+	if q.Price(SideBid) != q.Bid || q.Price(SideAsk) != q.Ask {
+		t.Fatal("Price does not return the bid and the ask")
+	}
+
+	if _, err := NewQuote(1.1, 1.0, 4, ts); err == nil {
+		t.Fatal("NewQuote with a crossed market succeeded")
+	}
+	if _, err := NewQuote(0, 1.0, 4, ts); err == nil {
+		t.Fatal("NewQuote with a zero bid succeeded")
+	}
+}
+
+func TestQuoteBookConvert(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	cache, err := NewTTLCacheWithConfig[string, Quote](Config{DefaultTTL: time.Minute, Clock: clock})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+	book, err := NewQuoteBook(cache, "")
+	if err != nil {
+		t.Fatalf("NewQuoteBook: %v", err)
+	}
+	set := func(pair string, bid, ask string, pipDigits int32, ttl time.Duration) {
+		t.Helper()
+		q := Quote{Bid: MustParseDecimal(bid), Ask: MustParseDecimal(ask), PipDigits: pipDigits, Timestamp: clock.Now()}
+		if err := book.Set(CurrencyPair{Currency(pair[:3]), Currency(pair[4:])}, q, ttl); err != nil {
+			t.Fatalf("Set(%s): %v", pair, err)
+		}
+	}
+	set("USD/THB", "36.500", "36.520", 2, time.Minute)
+	set("USD/JPY", "150.00", "150.04", 2, 30*time.Second)
+	set("EUR/USD", "1.0800", "1.0802", 4, time.Minute)
+
+	d := MustParseDecimal
+	tests := []struct {
+		amount   string
+		from, to Currency
+		side     Side
+		want     string
+	}{
+		{"1000", "USD", "THB", SideBid, "36500.00"},
+		{"1000", "USD", "THB", SideAsk, "36520.00"},
+		{"1000", "USD", "THB", SideMid, "36510.00"},
+		{"1000", "USD", "JPY", SideBid, "150000"},   // yen has no minor units
+		{"10000", "THB", "USD", SideBid, "273.82"},  // 10000 / 36.520
+		{"10000", "JPY", "THB", SideBid, "2432.68"}, // 10000 / 150.04 × 36.500
+		{"99.999", "USD", "USD", SideMid, "100.00"},
+		{"0.125", "EUR", "USD", SideBid, "0.14"}, // 0.135 rounds half away from zero
+	}
+	for _, tt := range tests {
+		got, err := book.Convert(d(tt.amount), tt.from, tt.to, tt.side)
+		if err != nil {
+			t.Errorf("Convert(%s %s to %s): %v", tt.amount, tt.from, tt.to, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Convert(%s %s to %s, %s) = %s, want %s", tt.amount, tt.from, tt.to, tt.side, got, tt.want)
+		}
+	}
+
+	cross, err := book.Quote("JPY", "THB")
+	if err != nil {
+		t.Fatalf("Quote(JPY, THB): %v", err)
+	}
+	if cross.Source != RateCross || cross.TTL != 30*time.Second || cross.Quote.Bid.Cmp(cross.Quote.Ask) > 0 {
+		t.Fatalf("Quote(JPY, THB) = %+v, want a cross with the USD/JPY TTL", cross)
+	}
+
+	clock.Advance(30 * time.Second)
+	if _, err := book.Convert(d("1"), "JPY", "THB", SideBid); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("Convert after USD/JPY expired: %v, want ErrNoRate", err)
+	}
+	if err := book.Set(CurrencyPair{"USD", "THB"}, Quote{}); err == nil {
+		t.Fatal("Set with an empty quote succeeded")
+	}
+}
+
+func TestQuoteBookSkipsInvalidQuotes(t *testing.T) {
+	cache, err := NewQuoteCache(time.Minute)
+	if err != nil {
+		t.Fatalf("NewQuoteCache: %v", err)
+	}
+	defer cache.StopJanitor()
+	book, err := NewQuoteBook(cache, "")
+	if err != nil {
+		t.Fatalf("NewQuoteBook: %v", err)
+	}
+	// Stored around Set, so not validated; inverting it would divide by zero.
+	cache.Set("USD/THB", Quote{Bid: MustParseDecimal("0"), Ask: MustParseDecimal("36.52"), PipDigits: 2})
+
+	if _, err := book.Quote("THB", "USD"); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("Quote(THB, USD) from a zero bid: %v, want ErrNoRate", err)
+	}
+	if _, err := book.Convert(MustParseDecimal("1000"), "USD", "THB", SideBid); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("Convert with a zero bid: %v, want ErrNoRate", err)
+	}
+}
+
+func TestQuoteSnapshotRoundTrip(t *testing.T) {
+	cache, err := NewQuoteCache(time.Minute)
+	if err != nil {
+		t.Fatalf("NewQuoteCache: %v", err)
+	}
+	defer cache.StopJanitor()
+	q, _ := NewQuote(36.5, 36.52, 2, time.Unix(1_700_000_000, 0).UTC())
+	cache.Set("USD/THB", q)
+
+	var buf bytes.Buffer
+	if err := cache.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+	restored, err := NewQuoteCache(time.Minute)
+	if err != nil {
+		t.Fatalf("NewQuoteCache: %v", err)
+	}
+	defer restored.StopJanitor()
+	if err := restored.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	got, ok := restored.Get("USD/THB")
+	if !ok || got.Bid.String() != "36.500" || got.Ask.String() != "36.520" || !got.Timestamp.Equal(q.Timestamp) {
+		t.Fatalf("restored quote = %+v, want %+v", got, q)
+	}
+}
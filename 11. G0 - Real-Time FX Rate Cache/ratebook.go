@@ -0,0 +1,137 @@
+package fxcache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultPivot is the currency RateBook crosses through when none is given.
+const DefaultPivot Currency = "USD"
+
+// ErrNoRate is returned by RateBook.Rate when no combination of cached rates
+// gives the requested pair.
+var ErrNoRate = errors.New("no rate")
+
+// RateSource tells how RateBook derived a rate.
+type RateSource int
+
+const (
+	// RateDirect means the pair itself was cached.
+	RateDirect RateSource = iota
+	// RateInverse means the opposite pair was cached and inverted.
+	RateInverse
+	// RateCross means the rate was triangulated through the pivot currency.
+	RateCross
+)
+
+// String returns the source name.
+func (s RateSource) String() string {
+	switch s {
+	case RateDirect:
+		return "direct"
+	case RateInverse:
+		return "inverse"
+	case RateCross:
+		return "cross"
+	default:
+		return fmt.Sprintf("RateSource(%d)", int(s))
+	}
+}
+
+// DerivedRate is a rate answered by RateBook.
+type DerivedRate struct {
+	Pair CurrencyPair
+	Rate float64
+	// TTL is the shortest remaining TTL of the cached rates used.
+	TTL    time.Duration
+	Source RateSource
+}
+
+// RateBook answers rates for any currency pair from an FXRateCache keyed by
+// "BASE/QUOTE", deriving the ones that are not cached directly.
+type RateBook struct {
+	cache *FXRateCache
+	pivot Currency
+}
+
+// NewRateBook returns a RateBook over cache that crosses through pivot, or
+// DefaultPivot if pivot is empty.
+func NewRateBook(cache *FXRateCache, pivot Currency) (*RateBook, error) {
+	if pivot == "" {
+		pivot = DefaultPivot
+	}
+	if !pivot.Valid() {
+		return nil, fmt.Errorf("unknown pivot currency %q", pivot)
+	}
+	return &RateBook{cache: cache, pivot: pivot}, nil
+}
+
+// Set caches the rate of pair. The rate must be a positive finite number,
+// since RateBook inverts it.
+func (b *RateBook) Set(pair CurrencyPair, rate float64, ttl ...time.Duration) error {
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return fmt.Errorf("rate %v for %s must be a positive number", rate, pair)
+	}
+	b.cache.Set(pair.String(), rate, ttl...)
+	return nil
+}
+
+// Rate returns the rate of base/quote. It uses, in order of preference, the
+// cached pair itself, the inverse of the cached quote/base pair, or a cross
+// through the pivot currency whose two legs may each be direct or inverted.
+// The result expires with the first leg that expires. It returns an error
+// wrapping ErrNoRate if no such rates are cached.
+func (b *RateBook) Rate(base, quote Currency) (DerivedRate, error) {
+	pair, err := NewCurrencyPair(base, quote)
+	if err != nil {
+		return DerivedRate{}, err
+	}
+
+	rate, ttl, source, ok := derive(pair, b.pivot, b.lookup,
+		func(r float64) float64 { return 1 / r },
+		func(r1, r2 float64) float64 { return r1 * r2 })
+	if !ok {
+		return DerivedRate{}, fmt.Errorf("%w for %s", ErrNoRate, pair)
+	}
+	return DerivedRate{Pair: pair, Rate: rate, TTL: ttl, Source: source}, nil
+}
+
+// lookup returns a cached rate, skipping rates that cannot be inverted.
+func (b *RateBook) lookup(key string) (float64, time.Duration, bool) {
+	rate, ttl, ok := b.cache.GetWithExpiry(key)
+	return rate, ttl, ok && rate > 0
+}
+
+// derive finds the price of pair from cached prices of type T: the pair
+// itself, the inverse of the opposite pair, or a cross of the two legs
+// through pivot, each of which may be direct or inverted. lookup returns a
+// cached price and its remaining TTL by cache key; the TTL of a derived price
+// is the shortest of its legs'.
+func derive[T any](pair CurrencyPair, pivot Currency, lookup func(key string) (T, time.Duration, bool),
+	invert func(T) T, cross func(first, second T) T) (T, time.Duration, RateSource, bool) {
+	leg := func(p CurrencyPair) (T, time.Duration, RateSource, bool) {
+		if price, ttl, ok := lookup(p.String()); ok {
+			return price, ttl, RateDirect, true
+		}
+		if price, ttl, ok := lookup(p.Inverse().String()); ok {
+			return invert(price), ttl, RateInverse, true
+		}
+		var zero T
+		return zero, 0, 0, false
+	}
+
+	if price, ttl, source, ok := leg(pair); ok {
+		return price, ttl, source, true
+	}
+	if pair.Base != pivot && pair.Quote != pivot {
+		first, ttl1, _, ok1 := leg(CurrencyPair{Base: pair.Base, Quote: pivot})
+		second, ttl2, _, ok2 := leg(CurrencyPair{Base: pivot, Quote: pair.Quote})
+		if ok1 && ok2 {
+			return cross(first, second), min(ttl1, ttl2), RateCross, true
+		}
+	}
+	var zero T
+	return zero, 0, 0, false
+}
@@ -0,0 +1,89 @@
+package fxcache
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateBook(t *testing.T) {
+	cache, clock := newTestCache(t)
+	book, err := NewRateBook(cache, "")
+	if err != nil {
+		t.Fatalf("NewRateBook: %v", err)
+	}
+	set := func(pair string, rate float64, ttl time.Duration) {
+		t.Helper()
+		p, err := ParseCurrencyPair(pair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := book.Set(p, rate, ttl); err != nil {
+			t.Fatalf("Set(%s): %v", pair, err)
+		}
+	}
+	set("USD/THB", 36.5, time.Minute)
+	set("USD/JPY", 150, 30*time.Second)
+	set("EUR/USD", 1.08, 2*time.Minute)
+	clock.Advance(10 * time.Second)
+
+	tests := []struct {
+		base, quote Currency
+		rate        float64
+		ttl         time.Duration
+		source      RateSource
+	}{
+		{"USD", "THB", 36.5, 50 * time.Second, RateDirect},
+		{"THB", "USD", 1 / 36.5, 50 * time.Second, RateInverse},
+		{"JPY", "THB", 36.5 / 150, 20 * time.Second, RateCross},
+		{"EUR", "THB", 1.08 * 36.5, 50 * time.Second, RateCross},
+		{"THB", "EUR", 1 / 36.5 / 1.08, 50 * time.Second, RateCross},
+	}
+	for _, tt := range tests {
+		got, err := book.Rate(tt.base, tt.quote)
+		if err != nil {
+			t.Errorf("Rate(%s, %s): %v", tt.base, tt.quote, err)
+			continue
+		}
+		if math.Abs(got.Rate-tt.rate) > 1e-12 || got.TTL != tt.ttl || got.Source != tt.source {
+			t.Errorf("Rate(%s, %s) = %v %v %v, want %v %v %v", tt.base, tt.quote,
+				got.Rate, got.TTL, got.Source, tt.rate, tt.ttl, tt.source)
+		}
+	}
+
+	// Once the shortest leg expires the cross is gone too.
+	clock.Advance(20 * time.Second)
+	if _, err := book.Rate("JPY", "THB"); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("Rate(JPY, THB) after USD/JPY expired: %v, want ErrNoRate", err)
+	}
+	if _, err := book.Rate("GBP", "USD"); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("Rate(GBP, USD): %v, want ErrNoRate", err)
+	}
+	if _, err := book.Rate("USD", "XYZ"); err == nil || errors.Is(err, ErrNoRate) {
+		t.Fatalf("Rate(USD, XYZ): %v, want an invalid currency error", err)
+	}
+	if err := book.Set(CurrencyPair{"USD", "THB"}, 0); err == nil {
+		t.Fatal("Set with a zero rate succeeded")
+	}
+}
+
+func TestRateBookPivot(t *testing.T) {
+	cache, _ := newTestCache(t)
+	if _, err := NewRateBook(cache, "XYZ"); err == nil {
+		t.Fatal("NewRateBook with an unknown pivot succeeded")
+	}
+	book, err := NewRateBook(cache, "EUR")
+	if err != nil {
+		t.Fatalf("NewRateBook: %v", err)
+	}
+	cache.Set("EUR/GBP", 0.85)
+	cache.Set("EUR/CHF", 0.95)
+	got, err := book.Rate("GBP", "CHF")
+	if err != nil {
+		t.Fatalf("Rate(GBP, CHF): %v", err)
+	}
+	if math.Abs(got.Rate-0.95/0.85) > 1e-12 || got.Source != RateCross {
+		t.Fatalf("Rate(GBP, CHF) = %v via %v, want %v via cross", got.Rate, got.Source, 0.95/0.85)
+	}
+}
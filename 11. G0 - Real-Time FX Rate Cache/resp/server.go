@@ -0,0 +1,319 @@
+// Package resp serves an FX rate cache over a subset of the Redis
+// serialization protocol (RESP), so redis-cli and Redis client libraries can
+// read and write rates.
+//
+// Supported commands are GET, SET key value [EX s | PX ms], DEL, TTL, PTTL,
+// EXISTS, KEYS pattern, PING, QUIT and COMMAND (answered with an empty list so
+// redis-cli can start). Keys are currency pairs, normalized like the HTTP
+// API's, so "usd/thb" and "USD/THB" name the same rate. Values are rates and
+// must be positive numbers.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+)
+
+// Server accepts RESP connections for a cache.
+type Server struct {
+	// Writer receives SET and DEL, e.g. a cluster.Node that replicates them.
+	// NewServer sets it to the cache; change it before calling Serve.
+	Writer fxcache.RateWriter
+
+	cache *fxcache.FXRateCache
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer returns a server for cache.
+func NewServer(cache *fxcache.FXRateCache) *Server {
+	return &Server{
+		Writer:    cache,
+		cache:     cache,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("resp: server closed")
+
+// Serve accepts connections on ln and handles each on its own goroutine. It
+// always returns a non-nil error; after Close it is ErrServerClosed.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	s.listeners[ln] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, ln)
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+// ListenAndServe listens on addr and serves until the listener fails or Close
+// is called.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Close stops the listeners, closes every connection and waits for their
+// handlers to return.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	for ln := range s.listeners {
+		err = errors.Join(err, ln.Close())
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// handle runs the commands of one connection until it is closed.
+func (s *Server) handle(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := writer{bufio.NewWriter(conn)}
+	for {
+		args, err := readCommand(r)
+		if errors.Is(err, errProtocol) {
+			w.error("ERR " + err.Error())
+			w.Flush()
+			return
+		}
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.execute(w, args)
+		// Pipelined commands are answered together.
+		if r.Buffered() == 0 || quit {
+			if w.Flush() != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// execute runs one command and writes its reply. It reports whether the
+// client asked to close the connection.
+func (s *Server) execute(w writer, args []string) (quit bool) {
+	name := strings.ToUpper(args[0])
+	arity, ok := arities[name]
+	if !ok {
+		w.error("ERR unknown command '" + args[0] + "'")
+		return false
+	}
+	if arity >= 0 && len(args) != arity || arity < 0 && len(args) < -arity {
+		w.error("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+		return false
+	}
+
+	// Normalize the key arguments; SET has one, followed by its value.
+	switch name {
+	case "GET", "DEL", "EXISTS", "TTL", "PTTL":
+		if !normalizeKeys(w, args[1:]) {
+			return false
+		}
+	case "SET":
+		if !normalizeKeys(w, args[1:2]) {
+			return false
+		}
+	}
+
+	switch name {
+	case "PING":
+		if len(args) > 1 {
+			w.bulk(args[1])
+		} else {
+			w.simple("PONG")
+		}
+	case "QUIT":
+		w.simple("OK")
+		return true
+	case "COMMAND":
+		w.array(nil)
+	case "GET":
+		if rate, ok := s.cache.Get(args[1]); ok {
+			w.bulk(formatRate(rate))
+		} else {
+			w.null()
+		}
+	case "SET":
+		s.set(w, args)
+	case "DEL":
+		var n int64
+		for _, key := range args[1:] {
+			if s.Writer.Delete(key) {
+				n++
+			}
+		}
+		w.integer(n)
+	case "EXISTS":
+		var n int64
+		for _, key := range args[1:] {
+			if s.cache.Has(key) {
+				n++
+			}
+		}
+		w.integer(n)
+	case "TTL", "PTTL":
+		_, ttl, ok := s.cache.GetWithExpiry(args[1])
+		switch {
+		case !ok:
+			w.integer(-2)
+		case name == "TTL":
+			w.integer(int64((ttl + time.Second/2) / time.Second))
+		default:
+			w.integer(ttl.Milliseconds())
+		}
+	case "KEYS":
+		var keys []string
+		for _, key := range s.cache.Keys() {
+			if fxcache.MatchGlob(args[1], key) {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		w.array(keys)
+	}
+	return false
+}
+
+// arities is the number of arguments of each command, including its name.
+// A negative arity -n means at least n.
+var arities = map[string]int{
+	"PING":    -1,
+	"QUIT":    1,
+	"COMMAND": -1,
+	"GET":     2,
+	"SET":     -3,
+	"DEL":     -2,
+	"EXISTS":  -2,
+	"TTL":     2,
+	"PTTL":    2,
+	"KEYS":    2,
+}
+
+// normalizeKeys replaces each key by its currency pair in canonical form. If
+// a key is not a pair, it writes the error and returns false.
+func normalizeKeys(w writer, keys []string) bool {
+	for i, key := range keys {
+		pair, err := fxcache.ParseCurrencyPair(key)
+		if err != nil {
+			w.error("ERR " + err.Error())
+			return false
+		}
+		keys[i] = pair.String()
+	}
+	return true
+}
+
+// set handles SET key value [EX seconds | PX milliseconds]. Without an
+// expiry the cache's default TTL applies, since every entry has one.
+func (s *Server) set(w writer, args []string) {
+	rate, err := strconv.ParseFloat(args[2], 64)
+	if err != nil {
+		w.error("ERR value is not a valid float")
+		return
+	}
+	// The same check and message as the HTTP and gRPC APIs.
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		w.error("ERR rate must be a positive number")
+		return
+	}
+
+	var ttl time.Duration
+	opts := args[3:]
+	for len(opts) > 0 {
+		unit := time.Duration(0)
+		switch strings.ToUpper(opts[0]) {
+		case "EX":
+			unit = time.Second
+		case "PX":
+			unit = time.Millisecond
+		}
+		if unit == 0 || len(opts) < 2 || ttl != 0 {
+			w.error("ERR syntax error")
+			return
+		}
+		n, err := strconv.ParseInt(opts[1], 10, 64)
+		if err != nil {
+			w.error("ERR value is not an integer or out of range")
+			return
+		}
+		if n <= 0 || n > int64(1<<62)/int64(unit) {
+			w.error("ERR invalid expire time in 'set' command")
+			return
+		}
+		ttl = time.Duration(n) * unit
+		opts = opts[2:]
+	}
+
+	s.Writer.Set(args[1], rate, ttl)
+	w.simple("OK")
+}
+
+// formatRate writes a rate with the fewest digits that read back exactly.
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
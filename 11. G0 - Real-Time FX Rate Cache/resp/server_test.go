@@ -0,0 +1,249 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	fxcache "g0-real-time-fx-rate-cache"
+)
+
+// client is a minimal RESP client for the tests.
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newTestServer(t *testing.T) (*client, *fxcache.ManualClock) {
+	t.Helper()
+	clock := fxcache.NewManualClock(time.Unix(1_700_000_000, 0))
+	cache, err := fxcache.NewTTLCacheWithConfig[string, float64](fxcache.Config{
+		DefaultTTL:      time.Minute,
+		JanitorInterval: time.Minute,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(cache)
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Close()
+		if err := <-served; !errors.Is(err, ErrServerClosed) {
+			t.Errorf("Serve returned %v, want ErrServerClosed", err)
+		}
+		cache.StopJanitor()
+	})
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}, clock
+}
+
+// do sends args as a RESP array and returns the reply in a compact form:
+// "+OK", "-ERR ...", ":1", "$value", "$nil" or "[a b]" for arrays.
+func (c *client) do(args ...string) string {
+	c.t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	c.send(b.String())
+	return c.reply()
+}
+
+func (c *client) send(raw string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.conn, raw); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) reply() string {
+	c.t.Helper()
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("read reply: %v", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	switch line[0] {
+	case '$':
+		n, _ := strconv.Atoi(line[1:])
+		if n < 0 {
+			return "$nil"
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			c.t.Fatal(err)
+		}
+		return "$" + string(buf[:n])
+	case '*':
+		n, _ := strconv.Atoi(line[1:])
+		items := make([]string, n)
+		for i := range items {
+			items[i] = strings.TrimPrefix(c.reply(), "$")
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	}
+	return line
+}
+
+func TestCommands(t *testing.T) {
+	c, clock := newTestServer(t)
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"PING"}, "+PONG"},
+		{[]string{"ping", "hello"}, "$hello"},
+		{[]string{"GET", "USD/THB"}, "$nil"},
+		{[]string{"SET", "USD/THB", "36.5", "PX", "1500"}, "+OK"},
+		{[]string{"SET", "EUR/USD", "1.08"}, "+OK"},
+		{[]string{"SET", "USD/JPY", "151.2", "EX", "10"}, "+OK"},
+		{[]string{"GET", "USD/THB"}, "$36.5"},
+		{[]string{"PTTL", "USD/THB"}, ":1500"},
+		{[]string{"TTL", "USD/THB"}, ":2"},
+		{[]string{"TTL", "EUR/USD"}, ":60"},
+		{[]string{"TTL", "GBP/USD"}, ":-2"},
+		{[]string{"EXISTS", "USD/THB", "EUR/USD", "GBP/USD"}, ":2"},
+		{[]string{"KEYS", "USD/*"}, "[USD/JPY USD/THB]"},
+		{[]string{"KEYS", "*"}, "[EUR/USD USD/JPY USD/THB]"},
+		{[]string{"DEL", "USD/JPY", "GBP/USD"}, ":1"},
+		{[]string{"COMMAND", "DOCS"}, "[]"},
+	}
+	for _, step := range steps {
+		if got := c.do(step.args...); got != step.want {
+			t.Fatalf("%v = %q, want %q", step.args, got, step.want)
+		}
+	}
+
+	clock.Advance(1500 * time.Millisecond)
+	if got := c.do("GET", "USD/THB"); got != "$nil" {
+		t.Fatalf("GET after PX expiry = %q, want nil", got)
+	}
+	if got := c.do("PTTL", "USD/THB"); got != ":-2" {
+		t.Fatalf("PTTL after expiry = %q, want -2", got)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	c, _ := newTestServer(t)
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"FLUSHALL"}, "-ERR unknown command 'FLUSHALL'"},
+		{[]string{"GET"}, "-ERR wrong number of arguments for 'get' command"},
+		{[]string{"SET", "USD/THB", "abc"}, "-ERR value is not a valid float"},
+		{[]string{"SET", "USD/THB", "0"}, "-ERR rate must be a positive number"},
+		{[]string{"SET", "USD/THB", "-36.5"}, "-ERR rate must be a positive number"},
+		{[]string{"SET", "USD/THB", "inf"}, "-ERR rate must be a positive number"},
+		{[]string{"SET", "USD/THB", "36.5", "PX"}, "-ERR syntax error"},
+		{[]string{"SET", "USD/THB", "36.5", "NX"}, "-ERR syntax error"},
+		{[]string{"SET", "USD/THB", "36.5", "PX", "x"}, "-ERR value is not an integer or out of range"},
+		{[]string{"SET", "USD/THB", "36.5", "PX", "0"}, "-ERR invalid expire time in 'set' command"},
+		{[]string{"EXISTS", "USD/THB"}, ":0"},
+		{[]string{"SET", "USDTHB", "36.5"}, `-ERR invalid currency pair "USDTHB", want e.g. USD/THB`},
+		{[]string{"GET", "USDTHB"}, `-ERR invalid currency pair "USDTHB", want e.g. USD/THB`},
+		{[]string{"DEL", "USD/THB", "x"}, `-ERR invalid currency pair "x", want e.g. USD/THB`},
+	}
+	for _, step := range steps {
+		if got := c.do(step.args...); got != step.want {
+			t.Fatalf("%v = %q, want %q", step.args, got, step.want)
+		}
+	}
+}
+
+func TestKeysAreNormalized(t *testing.T) {
+	c, _ := newTestServer(t)
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"SET", "usd/thb", "36.5"}, "+OK"},
+		{[]string{"GET", "USD/THB"}, "$36.5"},
+		{[]string{"GET", " Usd/Thb "}, "$36.5"},
+		{[]string{"EXISTS", "usd/thb"}, ":1"},
+		{[]string{"KEYS", "*"}, "[USD/THB]"},
+		{[]string{"DEL", "usd/THB"}, ":1"},
+	}
+	for _, step := range steps {
+		if got := c.do(step.args...); got != step.want {
+			t.Fatalf("%v = %q, want %q", step.args, got, step.want)
+		}
+	}
+}
+
+func TestInlineAndPipelinedCommands(t *testing.T) {
+	c, _ := newTestServer(t)
+
+	// telnet-style inline commands.
+	c.send("SET USD/THB 36.5\r\nGET USD/THB\n")
+	if got := c.reply(); got != "+OK" {
+		t.Fatalf("inline SET = %q, want +OK", got)
+	}
+	if got := c.reply(); got != "$36.5" {
+		t.Fatalf("inline GET = %q, want 36.5", got)
+	}
+
+	// Several commands in one write are all answered, in order.
+	c.send("*1\r\n$4\r\nPING\r\n*2\r\n$6\r\nEXISTS\r\n$7\r\nUSD/THB\r\n*1\r\n$4\r\nQUIT\r\n")
+	for _, want := range []string{"+PONG", ":1", "+OK"} {
+		if got := c.reply(); got != want {
+			t.Fatalf("pipelined reply = %q, want %q", got, want)
+		}
+	}
+	if _, err := c.r.ReadByte(); err != io.EOF {
+		t.Fatalf("read after QUIT: %v, want EOF", err)
+	}
+}
+
+func TestProtocolErrorClosesConnection(t *testing.T) {
+	c, _ := newTestServer(t)
+
+	c.send("*1\r\n+PING\r\n")
+	if got := c.reply(); !strings.HasPrefix(got, "-ERR protocol error") {
+		t.Fatalf("reply = %q, want a protocol error", got)
+	}
+	if _, err := c.r.ReadByte(); err != io.EOF {
+		t.Fatalf("read after protocol error: %v, want EOF", err)
+	}
+}
+
+func TestCommandTooLong(t *testing.T) {
+	c, _ := newTestServer(t)
+
+	// Each argument is within maxBulkLen, but together they exceed
+	// maxCommandLen; the server gives up before reading them all.
+	arg := strings.Repeat("x", maxBulkLen)
+	n := maxCommandLen/maxBulkLen + 1
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", n+1)
+	b.WriteString("$4\r\nPING\r\n")
+	for range n {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	go io.WriteString(c.conn, b.String())
+	if got := c.reply(); got != "-ERR protocol error: command too long" {
+		t.Fatalf("reply = %q, want a protocol error", got)
+	}
+}
@@ -0,0 +1,367 @@
+package fxcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultShardCount is the number of shards used when NewShardedTTLCache is
+// given zero.
+const DefaultShardCount = 16
+
+// ShardedTTLCache spreads its entries over several independent TTLCache
+// shards, each with its own lock and janitor, so that writers and janitor
+// sweeps on one shard do not block readers of the others. Keys are routed to
+// a shard by hash. It offers the same methods as TTLCache.
+type ShardedTTLCache[K comparable, V any] struct {
+	shards []*TTLCache[K, V]
+	seed   maphash.Seed
+}
+
+// NewShardedTTLCache creates a cache with the given number of shards, each
+// built from cfg. Capacity limits in cfg apply to the whole cache and are
+// split evenly between the shards, so eviction is per shard and approximate.
+// Automatic snapshots and the write-ahead log are not supported; call
+// SaveSnapshot and LoadSnapshot instead.
+func NewShardedTTLCache[K comparable, V any](shards int, cfg Config) (*ShardedTTLCache[K, V], error) {
+	if shards < 0 {
+		return nil, fmt.Errorf("shard count must not be negative")
+	}
+	if cfg.SnapshotPath != "" {
+		return nil, fmt.Errorf("snapshot path is not supported by a sharded cache")
+	}
+	if cfg.WALPath != "" {
+		return nil, fmt.Errorf("write-ahead log is not supported by a sharded cache")
+	}
+	if shards == 0 {
+		shards = DefaultShardCount
+	}
+
+	shardCfg := cfg
+	if cfg.MaxEntries > 0 {
+		shardCfg.MaxEntries = (cfg.MaxEntries + shards - 1) / shards
+	}
+	if cfg.MaxBytes > 0 {
+		shardCfg.MaxBytes = (cfg.MaxBytes + int64(shards) - 1) / int64(shards)
+	}
+
+	s := &ShardedTTLCache[K, V]{
+		shards: make([]*TTLCache[K, V], shards),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range s.shards {
+		shard, err := NewTTLCacheWithConfig[K, V](shardCfg)
+		if err != nil {
+			s.Close(context.Background())
+			return nil, err
+		}
+		s.shards[i] = shard
+	}
+	return s, nil
+}
+
+// shard returns the shard that owns key.
+func (s *ShardedTTLCache[K, V]) shard(key K) *TTLCache[K, V] {
+	return s.shards[maphash.Comparable(s.seed, key)%uint64(len(s.shards))]
+}
+
+// StopJanitor stops the janitor of every shard without waiting, as
+// TTLCache.StopJanitor does; the shards stay usable. It is safe to call more
+// than once.
+func (s *ShardedTTLCache[K, V]) StopJanitor() {
+	for _, shard := range s.shards {
+		if shard != nil {
+			shard.StopJanitor()
+		}
+	}
+}
+
+// Close closes every shard, as TTLCache.Close does. The shards shut down
+// concurrently; it returns ctx.Err() if ctx is done before they all have.
+func (s *ShardedTTLCache[K, V]) Close(ctx context.Context) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.shards))
+	for i, shard := range s.shards {
+		if shard == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = shard.Close(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Set adds or updates a key-value pair. See TTLCache.Set.
+func (s *ShardedTTLCache[K, V]) Set(key K, value V, ttl ...time.Duration) {
+	s.shard(key).Set(key, value, ttl...)
+}
+
+// Get retrieves a live value. See TTLCache.Get.
+func (s *ShardedTTLCache[K, V]) Get(key K) (V, bool) {
+	return s.shard(key).Get(key)
+}
+
+// GetWithExpiry retrieves a live value and its remaining TTL. See
+// TTLCache.GetWithExpiry.
+func (s *ShardedTTLCache[K, V]) GetWithExpiry(key K) (V, time.Duration, bool) {
+	return s.shard(key).GetWithExpiry(key)
+}
+
+// GetOrLoad retrieves a value, loading it on a miss. Misses are deduplicated
+// per key within its shard. See TTLCache.GetOrLoad.
+func (s *ShardedTTLCache[K, V]) GetOrLoad(ctx context.Context, key K, loader LoaderFunc[K, V], ttl ...time.Duration) (V, error) {
+	return s.shard(key).GetOrLoad(ctx, key, loader, ttl...)
+}
+
+// SetLoader registers the background refresh loader on every shard.
+func (s *ShardedTTLCache[K, V]) SetLoader(loader LoaderFunc[K, V]) {
+	for _, shard := range s.shards {
+		shard.SetLoader(loader)
+	}
+}
+
+// OnEvicted registers an eviction callback on every shard.
+func (s *ShardedTTLCache[K, V]) OnEvicted(fn EvictionFunc[K, V]) {
+	for _, shard := range s.shards {
+		shard.OnEvicted(fn)
+	}
+}
+
+// OnSet registers a set callback on every shard.
+func (s *ShardedTTLCache[K, V]) OnSet(fn SetFunc[K, V]) {
+	for _, shard := range s.shards {
+		shard.OnSet(fn)
+	}
+}
+
+// Subscribe returns a channel of the changes to keys selected by filter on
+// every shard. See TTLCache.Subscribe; events for one key still arrive in
+// order, since a key always lives on the same shard.
+func (s *ShardedTTLCache[K, V]) Subscribe(filter Filter[K], opts ...SubscribeOptions) (<-chan Event[K, V], func(), error) {
+	sub, err := newSubscriber[K, V](filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, shard := range s.shards {
+		if err := shard.subscribe(sub); err != nil {
+			for _, shard := range s.shards[:i] {
+				shard.unsubscribe(sub)
+			}
+			return nil, nil, err
+		}
+	}
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			for _, shard := range s.shards {
+				shard.unsubscribe(sub)
+			}
+			sub.close()
+		})
+	}, nil
+}
+
+// Delete removes key. See TTLCache.Delete.
+func (s *ShardedTTLCache[K, V]) Delete(key K) bool {
+	return s.shard(key).Delete(key)
+}
+
+// Expire removes key as if it had expired. See TTLCache.Expire.
+func (s *ShardedTTLCache[K, V]) Expire(key K) bool {
+	return s.shard(key).Expire(key)
+}
+
+// Has reports whether key has a live entry.
+func (s *ShardedTTLCache[K, V]) Has(key K) bool {
+	return s.shard(key).Has(key)
+}
+
+// Touch extends the TTL of a live entry. See TTLCache.Touch.
+func (s *ShardedTTLCache[K, V]) Touch(key K, ttl ...time.Duration) bool {
+	return s.shard(key).Touch(key, ttl...)
+}
+
+// SetUntil stores value with an absolute expiry time. See TTLCache.SetUntil.
+func (s *ShardedTTLCache[K, V]) SetUntil(key K, value V, expiresAt time.Time) bool {
+	return s.shard(key).SetUntil(key, value, expiresAt)
+}
+
+// SetIfAbsent stores value only if key has no live entry.
+func (s *ShardedTTLCache[K, V]) SetIfAbsent(key K, value V, ttl ...time.Duration) bool {
+	return s.shard(key).SetIfAbsent(key, value, ttl...)
+}
+
+// CompareAndSwap stores new only if key's live entry equals old. See
+// TTLCache.CompareAndSwap.
+func (s *ShardedTTLCache[K, V]) CompareAndSwap(key K, old, new V, ttl ...time.Duration) bool {
+	return s.shard(key).CompareAndSwap(key, old, new, ttl...)
+}
+
+// Len returns the number of live entries across all shards.
+func (s *ShardedTTLCache[K, V]) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Keys returns the keys of all live entries in no particular order.
+func (s *ShardedTTLCache[K, V]) Keys() []K {
+	var keys []K
+	for _, shard := range s.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// Range calls fn for each live entry, one shard at a time, until fn returns false.
+func (s *ShardedTTLCache[K, V]) Range(fn func(key K, value V) bool) {
+	more := true
+	for _, shard := range s.shards {
+		shard.Range(func(key K, value V) bool {
+			more = fn(key, value)
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+// Clear removes every entry from every shard.
+func (s *ShardedTTLCache[K, V]) Clear() {
+	for _, shard := range s.shards {
+		shard.Clear()
+	}
+}
+
+// SetMany stores all entries, taking each shard's lock once.
+func (s *ShardedTTLCache[K, V]) SetMany(entries map[K]V, ttl ...time.Duration) {
+	byShard := make(map[*TTLCache[K, V]]map[K]V)
+	for key, value := range entries {
+		shard := s.shard(key)
+		if byShard[shard] == nil {
+			byShard[shard] = make(map[K]V)
+		}
+		byShard[shard][key] = value
+	}
+	for shard, batch := range byShard {
+		shard.SetMany(batch, ttl...)
+	}
+}
+
+// GetMany looks up several keys, taking each shard's lock once.
+func (s *ShardedTTLCache[K, V]) GetMany(keys []K) map[K]V {
+	byShard := make(map[*TTLCache[K, V]][]K)
+	for _, key := range keys {
+		shard := s.shard(key)
+		byShard[shard] = append(byShard[shard], key)
+	}
+	found := make(map[K]V, len(keys))
+	for shard, batch := range byShard {
+		for key, value := range shard.GetMany(batch) {
+			found[key] = value
+		}
+	}
+	return found
+}
+
+// GetManyWithExpiry is GetMany that also returns the remaining TTLs. See
+// TTLCache.GetManyWithExpiry; each shard is read at its own instant.
+func (s *ShardedTTLCache[K, V]) GetManyWithExpiry(keys []K) (map[K]V, map[K]time.Duration) {
+	byShard := make(map[*TTLCache[K, V]][]K)
+	for _, key := range keys {
+		shard := s.shard(key)
+		byShard[shard] = append(byShard[shard], key)
+	}
+	found := make(map[K]V, len(keys))
+	ttls := make(map[K]time.Duration, len(keys))
+	for shard, batch := range byShard {
+		values, shardTTLs := shard.GetManyWithExpiry(batch)
+		for key, value := range values {
+			found[key] = value
+			ttls[key] = shardTTLs[key]
+		}
+	}
+	return found, ttls
+}
+
+// Evictions returns the number of capacity evictions across all shards.
+func (s *ShardedTTLCache[K, V]) Evictions() uint64 {
+	var n uint64
+	for _, shard := range s.shards {
+		n += shard.Evictions()
+	}
+	return n
+}
+
+// Stats returns the counters summed over all shards. LastSweepDuration is
+// the longest of the shards' most recent sweeps.
+func (s *ShardedTTLCache[K, V]) Stats() Stats {
+	var total Stats
+	for _, shard := range s.shards {
+		st := shard.Stats()
+		total.Hits += st.Hits
+		total.Misses += st.Misses
+		total.Sets += st.Sets
+		total.Expirations += st.Expirations
+		total.Evictions += st.Evictions
+		total.Entries += st.Entries
+		total.Bytes += st.Bytes
+		total.DroppedEvents += st.DroppedEvents
+		total.LastSweepDuration = max(total.LastSweepDuration, st.LastSweepDuration)
+	}
+	return total
+}
+
+// SaveSnapshot writes the live entries of every shard to w in the same format
+// as TTLCache.SaveSnapshot.
+func (s *ShardedTTLCache[K, V]) SaveSnapshot(w io.Writer) error {
+	snap := snapshot[K, V]{Version: SnapshotVersion}
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		now := shard.clock.Now().UnixMilli()
+		snap.SavedAt = now
+		snap.Entries = append(snap.Entries, shard.snapshotEntriesLocked(now)...)
+		shard.mu.RUnlock()
+	}
+	if err := json.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot on a TTLCache or a
+// ShardedTTLCache and routes its entries to their shards.
+func (s *ShardedTTLCache[K, V]) LoadSnapshot(r io.Reader) error {
+	var snap snapshot[K, V]
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("decode snapshot: %w", err)
+	}
+	if snap.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d, want %d", snap.Version, SnapshotVersion)
+	}
+
+	byShard := make(map[*TTLCache[K, V]][]snapshotEntry[K, V])
+	for _, e := range snap.Entries {
+		shard := s.shard(e.Key)
+		byShard[shard] = append(byShard[shard], e)
+	}
+	for shard, entries := range byShard {
+		if err := shard.loadEntries(entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package fxcache
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestShardedTTLCache(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	cache, err := NewShardedTTLCache[string, float64](4, Config{
+		DefaultTTL:      time.Second,
+		JanitorInterval: time.Second,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewShardedTTLCache: %v", err)
+	}
+	defer cache.StopJanitor()
+
+	pairs := benchPairs(100)
+	for i, pair := range pairs {
+		cache.Set(pair, float64(i))
+	}
+	if got := cache.Len(); got != len(pairs) {
+		t.Fatalf("Len() = %d, want %d", got, len(pairs))
+	}
+	for i, pair := range pairs {
+		if val, ok := cache.Get(pair); !ok || val != float64(i) {
+			t.Fatalf("Get(%q) = (%v, %v), want (%v, true)", pair, val, ok, float64(i))
+		}
+	}
+
+	got := cache.GetMany(pairs[:10])
+	if len(got) != 10 {
+		t.Fatalf("GetMany returned %d entries, want 10", len(got))
+	}
+
+	// Every shard's janitor sweeps on its own timer.
+	clock.Advance(time.Second)
+	clock.BlockUntil(len(cache.shards))
+	for _, shard := range cache.shards {
+		shard.mu.RLock()
+		n := len(shard.cache)
+		shard.mu.RUnlock()
+		if n != 0 {
+			t.Fatalf("shard still holds %d expired entries", n)
+		}
+	}
+}
+
+func benchPairs(n int) []string {
+	pairs := make([]string, n)
+	for i := range pairs {
+		pairs[i] = fmt.Sprintf("C%03d/USD", i)
+	}
+	return pairs
+}
+
+// fxCache is the subset of methods shared by TTLCache and ShardedTTLCache
+// that the benchmarks exercise.
+type fxCache interface {
+	Set(key string, value float64, ttl ...time.Duration)
+	Get(key string) (float64, bool)
+	StopJanitor()
+}
+
+// benchmarkMixed runs readers and writers in parallel against the same cache
+// with the janitor running. writePercent of the operations are
+// writes.
+func benchmarkMixed(b *testing.B, cache fxCache, writePercent int) {
+	defer cache.StopJanitor()
+
+	pairs := benchPairs(10_000)
+	for i, pair := range pairs {
+		cache.Set(pair, float64(i))
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+		for pb.Next() {
+			pair := pairs[r.IntN(len(pairs))]
+			if r.IntN(100) < writePercent {
+				cache.Set(pair, r.Float64())
+			} else {
+				cache.Get(pair)
+			}
+		}
+	})
+}
+
+func BenchmarkMixedContention(b *testing.B) {
+	for _, writePercent := range []int{1, 10, 50} {
+		b.Run(fmt.Sprintf("single/writes=%d%%", writePercent), func(b *testing.B) {
+			cache, err := NewFXRateCache(time.Minute)
+			if err != nil {
+				b.Fatal(err)
+			}
+			benchmarkMixed(b, cache, writePercent)
+		})
+		b.Run(fmt.Sprintf("sharded/writes=%d%%", writePercent), func(b *testing.B) {
+			cache, err := NewShardedTTLCache[string, float64](DefaultShardCount, Config{DefaultTTL: time.Minute})
+			if err != nil {
+				b.Fatal(err)
+			}
+			benchmarkMixed(b, cache, writePercent)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package fxcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format written by
+// SaveSnapshot. LoadSnapshot rejects snapshots with any other version.
+const SnapshotVersion = 1
+
+// snapshot is the JSON document written by SaveSnapshot.
+type snapshot[K comparable, V any] struct {
+	Version int `json:"version"`
+	// SavedAt is the Unix timestamp in milliseconds when the snapshot was taken.
+	SavedAt int64                 `json:"saved_at"`
+	Entries []snapshotEntry[K, V] `json:"entries"`
+}
+
+// snapshotEntry is one entry of a snapshot. ExpiresAt is absolute, so an
+// entry keeps its deadline across a restart.
+type snapshotEntry[K comparable, V any] struct {
+	Key       K     `json:"key"`
+	Value     V     `json:"value"`
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// SaveSnapshot writes every live entry with its absolute expiry time to w as
+// versioned JSON. K and V must be encodable with encoding/json.
+func (c *TTLCache[K, V]) SaveSnapshot(w io.Writer) error {
+	c.mu.RLock()
+	now := c.clock.Now().UnixMilli()
+	snap := snapshot[K, V]{Version: SnapshotVersion, SavedAt: now, Entries: c.snapshotEntriesLocked(now)}
+	c.mu.RUnlock()
+
+	if err := json.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	return nil
+}
+
+// snapshotEntriesLocked copies the live entries. It must be called with c.mu held.
+func (c *TTLCache[K, V]) snapshotEntriesLocked(now int64) []snapshotEntry[K, V] {
+	entries := make([]snapshotEntry[K, V], 0, len(c.cache))
+	for key, item := range c.cache {
+		if now < item.ExpiresAt {
+			entries = append(entries, snapshotEntry[K, V]{Key: key, Value: item.Value, ExpiresAt: item.ExpiresAt})
+		}
+	}
+	return entries
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot and stores its
+// entries with their original expiry times. Entries that have expired since
+// the snapshot was taken are skipped. Existing entries with the same keys are
+// overwritten; other entries are kept.
+func (c *TTLCache[K, V]) LoadSnapshot(r io.Reader) error {
+	var snap snapshot[K, V]
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("decode snapshot: %w", err)
+	}
+	if snap.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d, want %d", snap.Version, SnapshotVersion)
+	}
+
+	return c.loadEntries(snap.Entries)
+}
+
+// loadEntries stores the snapshot entries that have not expired yet.
+func (c *TTLCache[K, V]) loadEntries(entries []snapshotEntry[K, V]) error {
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	now := c.clock.Now().UnixMilli()
+	for _, e := range entries {
+		if now < e.ExpiresAt {
+			c.storeLocked(e.Key, e.Value, now, e.ExpiresAt)
+		}
+	}
+	return nil
+}
+
+// SaveSnapshotFile writes a snapshot to path atomically: it is written to a
+// temporary file in the same directory, synced, and renamed over path, so a
+// crash never leaves a partial snapshot behind.
+func (c *TTLCache[K, V]) SaveSnapshotFile(path string) error {
+	return writeFileAtomic(path, c.SaveSnapshot)
+}
+
+// LoadSnapshotFile loads the snapshot at path. A missing file is not an
+// error, so a first start with an empty cache works.
+func (c *TTLCache[K, V]) LoadSnapshotFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.LoadSnapshot(f)
+}
+
+// writeFileAtomic calls write with a temporary file next to path and renames
+// it over path once it is fully written and synced.
+func writeFileAtomic(path string, write func(io.Writer) error) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// startSnapshots writes a snapshot to path every interval until Close, and
+// once more on the way out.
+func (c *TTLCache[K, V]) startSnapshots(path string, interval time.Duration) {
+	timer := c.clock.NewTimer(interval)
+	save := func() {
+		if err := c.SaveSnapshotFile(path); err != nil {
+			c.onError(fmt.Errorf("snapshot to %s: %w", path, err))
+		}
+	}
+
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		for {
+			select {
+			case <-timer.C():
+				save()
+				timer.Reset(interval)
+			case <-c.stopWorkers:
+				timer.Stop()
+				save()
+				return
+			}
+		}
+	}()
+}
+
+// logError is the default Config.OnError handler.
+func logError(err error) {
+	log.Printf("ttlcache: %v", err)
+}
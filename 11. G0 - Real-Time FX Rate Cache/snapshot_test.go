@@ -0,0 +1,79 @@
+package fxcache
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	src, clock := newTestCache(t)
+	src.Set("USD/THB", 36.5, time.Second)
+	src.Set("AUD/USD", 0.66, 100*time.Millisecond)
+
+	var buf bytes.Buffer
+	if err := src.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+
+	// Restore 200ms later into a cache on the same clock.
+	clock.Advance(200 * time.Millisecond)
+	dst, err := NewTTLCacheWithConfig[string, float64](Config{DefaultTTL: time.Hour, JanitorInterval: time.Hour, Clock: clock})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer dst.StopJanitor()
+	if err := dst.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+
+	if val, ttl, ok := dst.GetWithExpiry("USD/THB"); !ok || val != 36.5 || ttl != 800*time.Millisecond {
+		t.Fatalf("restored USD/THB = (%v, %v, %v), want (36.5, 800ms, true)", val, ttl, ok)
+	}
+	if stored(dst, "AUD/USD") {
+		t.Fatal("expired AUD/USD was restored")
+	}
+}
+
+func TestLoadSnapshotRejectsUnknownVersion(t *testing.T) {
+	cache, _ := newTestCache(t)
+	err := cache.LoadSnapshot(strings.NewReader(`{"version":99,"entries":[]}`))
+	if err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Fatalf("LoadSnapshot error = %v, want unsupported version", err)
+	}
+}
+
+func TestPeriodicSnapshotWarmRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fx.snapshot")
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	cfg := Config{
+		DefaultTTL:       time.Hour,
+		JanitorInterval:  time.Hour,
+		SnapshotPath:     path,
+		SnapshotInterval: time.Minute,
+		Clock:            clock,
+		OnError:          func(err error) { t.Errorf("snapshot: %v", err) },
+	}
+
+	first, err := NewTTLCacheWithConfig[string, float64](cfg)
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	first.Set("EUR/USD", 1.08)
+	// Close writes a final snapshot before returning.
+	if err := first.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	second, err := NewTTLCacheWithConfig[string, float64](cfg)
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer second.Close(context.Background())
+	if val, ok := second.Get("EUR/USD"); !ok || val != 1.08 {
+		t.Fatalf("Get after warm restart = (%v, %v), want (1.08, true)", val, ok)
+	}
+}
@@ -0,0 +1,103 @@
+package fxcache
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Stats is a point-in-time view of a cache's counters. The counters are
+// cumulative since the cache was created.
+type Stats struct {
+	// Hits and Misses count lookups through Get, GetWithExpiry, GetMany and
+	// the first check of GetOrLoad. Has, Keys and Range are not counted.
+	Hits   uint64
+	Misses uint64
+	// Sets counts entries stored, by any method that writes a value
+	// (including loads and snapshot or log replay).
+	Sets uint64
+	// Expirations counts entries removed because their TTL passed.
+	Expirations uint64
+	// Evictions counts entries removed to respect MaxEntries or MaxBytes.
+	Evictions uint64
+	// Entries is the number of entries held, including expired ones the
+	// janitor has not removed yet, and Bytes their estimated size when
+	// MaxBytes is set.
+	Entries int
+	Bytes   int64
+	// DroppedEvents counts events not delivered to a Subscribe channel
+	// because its buffer was full.
+	DroppedEvents uint64
+	// LastSweepDuration is how long the janitor's most recent sweep took,
+	// measured in wall-clock time.
+	LastSweepDuration time.Duration
+}
+
+// HitRatio returns Hits / (Hits + Misses), or 0 before the first lookup.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Stats returns the cache's current counters.
+func (c *TTLCache[K, V]) Stats() Stats {
+	c.mu.RLock()
+	entries, bytes := len(c.cache), c.bytes
+	c.mu.RUnlock()
+
+	return Stats{
+		Hits:              c.hits.Load(),
+		Misses:            c.misses.Load(),
+		Sets:              c.sets.Load(),
+		Expirations:       c.expirations.Load(),
+		Evictions:         c.evictions.Load(),
+		Entries:           entries,
+		Bytes:             bytes,
+		DroppedEvents:     c.droppedEvents.Load(),
+		LastSweepDuration: time.Duration(c.lastSweepNanos.Load()),
+	}
+}
+
+// recordLookup counts a hit or a miss.
+func (c *TTLCache[K, V]) recordLookup(found bool) {
+	if found {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+// StatsProvider is implemented by TTLCache and ShardedTTLCache.
+type StatsProvider interface {
+	Stats() Stats
+}
+
+// NewMetricsHandler returns an http.Handler that serves the stats of p in the
+// Prometheus text exposition format, for scraping at e.g. /metrics.
+func NewMetricsHandler(p StatsProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		writeMetrics(&buf, p.Stats())
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
+
+// writeMetrics formats s as Prometheus metrics.
+func writeMetrics(buf *bytes.Buffer, s Stats) {
+	metric := func(name, kind, help string, value any) {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("ttlcache_hits_total", "counter", "Lookups that found a live entry.", s.Hits)
+	metric("ttlcache_misses_total", "counter", "Lookups that found no live entry.", s.Misses)
+	metric("ttlcache_sets_total", "counter", "Entries stored.", s.Sets)
+	metric("ttlcache_expirations_total", "counter", "Entries removed because their TTL passed.", s.Expirations)
+	metric("ttlcache_evictions_total", "counter", "Entries evicted to respect the capacity limits.", s.Evictions)
+	metric("ttlcache_subscriber_dropped_events_total", "counter", "Events dropped because a subscriber's buffer was full.", s.DroppedEvents)
+	metric("ttlcache_entries", "gauge", "Entries currently held.", s.Entries)
+	metric("ttlcache_bytes", "gauge", "Estimated size of the entries held, when MaxBytes is set.", s.Bytes)
+	metric("ttlcache_janitor_last_sweep_duration_seconds", "gauge", "Duration of the most recent janitor sweep.", s.LastSweepDuration.Seconds())
+}
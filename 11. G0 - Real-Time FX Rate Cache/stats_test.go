@@ -0,0 +1,62 @@
+package fxcache
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	cache, clock := newTestCache(t)
+
+	cache.Set("USD/THB", 36.5, 100*time.Millisecond)
+	cache.Set("EUR/USD", 1.08)
+	cache.Set("EUR/USD", 1.09)
+	cache.Get("USD/THB")
+	cache.Get("GBP/USD")
+	cache.GetMany([]string{"EUR/USD", "USD/JPY"})
+	cache.Has("EUR/USD")
+
+	// The janitor sweeps the expired entry an hour later.
+	clock.Advance(time.Hour)
+	clock.BlockUntil(1)
+
+	got := cache.Stats()
+	want := Stats{Hits: 2, Misses: 2, Sets: 3, Expirations: 2, Entries: 0}
+	got.LastSweepDuration = 0
+	if got != want {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+	if ratio := got.HitRatio(); ratio != 0.5 {
+		t.Fatalf("HitRatio() = %v, want 0.5", ratio)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	cache, _ := newTestCache(t)
+	cache.Set("USD/THB", 36.5)
+	cache.Get("USD/THB")
+	cache.Get("GBP/USD")
+
+	rec := httptest.NewRecorder()
+	NewMetricsHandler(cache).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Content-Type = %q, want Prometheus text format", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE ttlcache_hits_total counter",
+		"ttlcache_hits_total 1\n",
+		"ttlcache_misses_total 1\n",
+		"ttlcache_sets_total 1\n",
+		"# TYPE ttlcache_entries gauge",
+		"ttlcache_entries 1\n",
+		"ttlcache_janitor_last_sweep_duration_seconds 0\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("metrics output missing %q:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package fxcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Store, and by Tier.Get, when a key has no live
+// value.
+var ErrNotFound = errors.New("not found")
+
+// Store is a slower, shared key-value store that a Tier keeps a local
+// TTLCache in front of, such as Redis or a database. Values carry their
+// absolute expiry time, so every node's copy expires at the same moment.
+// Implementations must be safe for concurrent use.
+type Store[K comparable, V any] interface {
+	// Get returns the value of key and when it expires, or an error wrapping
+	// ErrNotFound if there is no live value.
+	Get(ctx context.Context, key K) (V, time.Time, error)
+	// Set stores the value of key until expiresAt.
+	Set(ctx context.Context, key K, value V, expiresAt time.Time) error
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key K) error
+}
+
+// MemoryStore is a Store held in memory. It stands in for a remote store in
+// tests, and can be shared by several Tiers in one process.
+type MemoryStore[K comparable, V any] struct {
+	mu      sync.RWMutex
+	entries map[K]CacheEntry[V]
+	clock   Clock
+}
+
+// NewMemoryStore returns an empty MemoryStore that expires values by clock,
+// or SystemClock if clock is nil.
+func NewMemoryStore[K comparable, V any](clock Clock) *MemoryStore[K, V] {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &MemoryStore[K, V]{entries: make(map[K]CacheEntry[V]), clock: clock}
+}
+
+// Get implements Store.
+func (s *MemoryStore[K, V]) Get(_ context.Context, key K) (V, time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[key]
+	if !ok || s.clock.Now().UnixMilli() >= e.ExpiresAt {
+		var zero V
+		return zero, time.Time{}, fmt.Errorf("key %v: %w", key, ErrNotFound)
+	}
+	return e.Value, time.UnixMilli(e.ExpiresAt), nil
+}
+
+// Set implements Store.
+func (s *MemoryStore[K, V]) Set(_ context.Context, key K, value V, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = CacheEntry[V]{Value: value, ExpiresAt: expiresAt.UnixMilli()}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore[K, V]) Delete(_ context.Context, key K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// FileStore is a Store that keeps one JSON file per key in a directory, so
+// processes on one machine, or sharing a network file system, can share it.
+// Files are replaced atomically, so a reader never sees a partial value. K
+// and V must be encodable with encoding/json.
+type FileStore[K comparable, V any] struct {
+	dir   string
+	clock Clock
+}
+
+// NewFileStore returns a FileStore in dir, creating the directory if needed.
+// Values are expired by clock, or SystemClock if clock is nil.
+func NewFileStore[K comparable, V any](dir string, clock Clock) (*FileStore[K, V], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create store %s: %w", dir, err)
+	}
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &FileStore[K, V]{dir: dir, clock: clock}, nil
+}
+
+// path returns the file of key. Keys are hashed, so any key gives a valid
+// file name of the same length.
+func (s *FileStore[K, V]) path(key K) (string, error) {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("encode key %v: %w", key, err)
+	}
+	sum := sha256.Sum256(data)
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// Get implements Store. It removes the file of an expired value.
+func (s *FileStore[K, V]) Get(_ context.Context, key K) (V, time.Time, error) {
+	var zero V
+	path, err := s.path(key)
+	if err != nil {
+		return zero, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return zero, time.Time{}, fmt.Errorf("key %v: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return zero, time.Time{}, err
+	}
+	var e snapshotEntry[K, V]
+	if err := json.Unmarshal(data, &e); err != nil {
+		return zero, time.Time{}, fmt.Errorf("decode %s: %w", path, err)
+	}
+	if s.clock.Now().UnixMilli() >= e.ExpiresAt {
+		os.Remove(path)
+		return zero, time.Time{}, fmt.Errorf("key %v: %w", key, ErrNotFound)
+	}
+	return e.Value, time.UnixMilli(e.ExpiresAt), nil
+}
+
+// Set implements Store.
+func (s *FileStore[K, V]) Set(_ context.Context, key K, value V, expiresAt time.Time) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(snapshotEntry[K, V]{Key: key, Value: value, ExpiresAt: expiresAt.UnixMilli()})
+	})
+}
+
+// Delete implements Store.
+func (s *FileStore[K, V]) Delete(_ context.Context, key K) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package fxcache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// testStore runs the Store contract against s, whose values expire by clock.
+func testStore(t *testing.T, s Store[string, float64], clock *ManualClock) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, _, err := s.Get(ctx, "USD/THB"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a missing key: err = %v, want ErrNotFound", err)
+	}
+	expiresAt := time.UnixMilli(clock.Now().Add(time.Minute).UnixMilli())
+	if err := s.Set(ctx, "USD/THB", 36.5, expiresAt); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	value, got, err := s.Get(ctx, "USD/THB")
+	if err != nil || value != 36.5 || !got.Equal(expiresAt) {
+		t.Errorf("Get = %v, %v, %v; want 36.5, %v, nil", value, got, err, expiresAt)
+	}
+
+	if err := s.Delete(ctx, "USD/THB"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := s.Get(ctx, "USD/THB"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "USD/THB"); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+
+	if err := s.Set(ctx, "EUR/USD", 1.08, clock.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	clock.Advance(time.Second)
+	if _, _, err := s.Get(ctx, "EUR/USD"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of an expired key: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	testStore(t, NewMemoryStore[string, float64](clock), clock)
+}
+
+func TestFileStore(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	dir := t.TempDir()
+	s, err := NewFileStore[string, float64](dir, clock)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	testStore(t, s, clock)
+
+	// A second store on the same directory, as in another process, shares
+	// the values.
+	if err := s.Set(context.Background(), "USD/JPY", 151.2, clock.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	other, err := NewFileStore[string, float64](dir, clock)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	if value, _, err := other.Get(context.Background(), "USD/JPY"); err != nil || value != 151.2 {
+		t.Errorf("Get from a second store = %v, %v; want 151.2, nil", value, err)
+	}
+
+	// The expired EUR/USD file was removed when it was read.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("store directory has %d files, want 1", len(entries))
+	}
+}
@@ -0,0 +1,262 @@
+package fxcache
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of change reported by Subscribe.
+type EventType int
+
+const (
+	// EventSet means a value was stored for a key that had no live entry.
+	EventSet EventType = iota
+	// EventUpdate means a live entry was overwritten with a new value.
+	EventUpdate
+	// EventExpire means an entry's TTL passed.
+	EventExpire
+	// EventDelete means an entry was removed explicitly, e.g. with Delete.
+	EventDelete
+	// EventEvict means an entry was evicted to respect MaxEntries or MaxBytes.
+	EventEvict
+)
+
+// String returns the event type name.
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventUpdate:
+		return "update"
+	case EventExpire:
+		return "expire"
+	case EventDelete:
+		return "delete"
+	case EventEvict:
+		return "evict"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(t))
+	}
+}
+
+// Event is one change delivered to a subscriber.
+type Event[K comparable, V any] struct {
+	Type EventType
+	Key  K
+	// Value is the new value for EventSet and EventUpdate, and the last value
+	// otherwise.
+	Value V
+	// ExpiresAt is when the new value expires, for EventSet and EventUpdate.
+	// It is the zero time for the other events.
+	ExpiresAt time.Time
+}
+
+// Filter selects the keys a subscriber receives events for. A nil Filter
+// selects every key.
+type Filter[K comparable] func(key K) bool
+
+// PrefixFilter selects keys that start with prefix, e.g. "USD/".
+func PrefixFilter(prefix string) Filter[string] {
+	return func(key string) bool { return strings.HasPrefix(key, prefix) }
+}
+
+// GlobFilter selects keys that match pattern, e.g. "USD/*". See MatchGlob.
+func GlobFilter(pattern string) Filter[string] {
+	return func(key string) bool { return MatchGlob(pattern, key) }
+}
+
+// OverflowPolicy decides what happens to an event for a subscriber whose
+// buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest drops the new event. It is the default.
+	OverflowDropNewest OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest buffered event to make room, so the
+	// subscriber always sees the most recent changes.
+	OverflowDropOldest
+	// OverflowBlock makes the writer (or the janitor) wait until the
+	// subscriber has room, so no event is lost. A stalled subscriber stalls
+	// the cache's writers, so it must keep reading until it cancels; in
+	// particular it must not write to the cache from the goroutine that reads
+	// the events.
+	OverflowBlock
+)
+
+// DefaultSubscribeBuffer is the buffer size used when SubscribeOptions.Buffer
+// is zero.
+const DefaultSubscribeBuffer = 64
+
+// SubscribeOptions configures a subscription. Zero values fall back to the
+// defaults.
+type SubscribeOptions struct {
+	// Buffer is the number of events buffered for the subscriber.
+	Buffer int
+	// Overflow is what happens when the buffer is full.
+	Overflow OverflowPolicy
+}
+
+// subscriber is one subscription. It may be registered on several shards.
+type subscriber[K comparable, V any] struct {
+	filter   Filter[K]
+	overflow OverflowPolicy
+	ch       chan Event[K, V]
+	// done is closed by cancel to release a publisher blocked on ch.
+	done chan struct{}
+
+	// mu guards ch against being closed while an event is sent on it.
+	mu        sync.Mutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+func newSubscriber[K comparable, V any](filter Filter[K], opts []SubscribeOptions) (*subscriber[K, V], error) {
+	var o SubscribeOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Buffer < 0 {
+		return nil, fmt.Errorf("subscribe buffer must not be negative")
+	}
+	if o.Overflow < OverflowDropNewest || o.Overflow > OverflowBlock {
+		return nil, fmt.Errorf("unknown overflow policy %d", o.Overflow)
+	}
+	if o.Buffer == 0 {
+		o.Buffer = DefaultSubscribeBuffer
+	}
+	return &subscriber[K, V]{
+		filter:   filter,
+		overflow: o.Overflow,
+		ch:       make(chan Event[K, V], o.Buffer),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Subscribe returns a channel of the changes to keys selected by filter, and
+// a function that cancels the subscription and closes the channel. Events for
+// one key arrive in the order the changes were made. Refreshing an entry's
+// TTL with Touch is not reported, and an overwritten entry is reported as a
+// single EventUpdate.
+//
+// Each subscriber has its own bounded buffer; opts sets its size and what
+// happens when it is full (see OverflowPolicy). Dropped events are counted in
+// Stats.DroppedEvents. It returns an error for invalid options.
+func (c *TTLCache[K, V]) Subscribe(filter Filter[K], opts ...SubscribeOptions) (<-chan Event[K, V], func(), error) {
+	sub, err := newSubscriber[K, V](filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.subscribe(sub); err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			c.unsubscribe(sub)
+			sub.close()
+		})
+	}, nil
+}
+
+// subscribe registers sub. The subscriber list is copied on write, so
+// publishers can use it without holding c.mu. It returns ErrClosed after
+// Close.
+func (c *TTLCache[K, V]) subscribe(sub *subscriber[K, V]) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	c.subscribers = append(slices.Clip(c.subscribers), sub)
+	return nil
+}
+
+func (c *TTLCache[K, V]) unsubscribe(sub *subscriber[K, V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribers = slices.DeleteFunc(slices.Clone(c.subscribers), func(s *subscriber[K, V]) bool { return s == sub })
+}
+
+// close releases a blocked publisher and closes the channel. It is called by
+// cancel and by Close, and only acts once.
+func (s *subscriber[K, V]) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		close(s.ch)
+	})
+}
+
+// publish delivers the queued notifications to the subscribers whose filter
+// matches. It reports how many events were dropped.
+func publish[K comparable, V any](subs []*subscriber[K, V], pending []notification[K, V]) (dropped uint64) {
+	for _, n := range pending {
+		e := Event[K, V]{Key: n.key, Value: n.value}
+		switch {
+		case n.set && n.replaced:
+			e.Type, e.ExpiresAt = EventUpdate, time.UnixMilli(n.expiresAt)
+		case n.set:
+			e.Type, e.ExpiresAt = EventSet, time.UnixMilli(n.expiresAt)
+		case n.reason == ReasonExpired:
+			e.Type = EventExpire
+		case n.reason == ReasonDeleted:
+			e.Type = EventDelete
+		case n.reason == ReasonCapacity:
+			e.Type = EventEvict
+		default:
+			// ReasonReplaced is reported by the EventUpdate that follows.
+			continue
+		}
+		for _, sub := range subs {
+			if sub.filter == nil || sub.filter(e.Key) {
+				dropped += sub.send(e)
+			}
+		}
+	}
+	return dropped
+}
+
+// send delivers e according to the overflow policy and returns the number of
+// events dropped.
+func (s *subscriber[K, V]) send(e Event[K, V]) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0
+	}
+
+	switch s.overflow {
+	case OverflowBlock:
+		select {
+		case s.ch <- e:
+		case <-s.done:
+		}
+		return 0
+	case OverflowDropOldest:
+		var dropped uint64
+		for {
+			select {
+			case s.ch <- e:
+				return dropped
+			default:
+			}
+			select {
+			case <-s.ch:
+				dropped++
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- e:
+			return 0
+		default:
+			return 1
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package fxcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// drainEvents returns the events buffered on ch as "type key=value" strings.
+func drainEvents(ch <-chan Event[string, float64]) []string {
+	var got []string
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return got
+			}
+			got = append(got, fmt.Sprintf("%s %s=%v", e.Type, e.Key, e.Value))
+		default:
+			return got
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	cache, clock := newTestCache(t)
+	usd, cancel, err := cache.Subscribe(GlobFilter("USD/*"))
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer cancel()
+	all, cancelAll, err := cache.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer cancelAll()
+
+	cache.Set("USD/THB", 36.5, time.Minute)
+	e := <-usd
+	if e.Type != EventSet || e.Key != "USD/THB" || !e.ExpiresAt.Equal(clock.Now().Add(time.Minute)) {
+		t.Fatalf("first event = %+v, want USD/THB set expiring in 1m", e)
+	}
+
+	cache.Set("USD/THB", 36.6, time.Minute)
+	cache.Set("EUR/USD", 1.08)
+	cache.Touch("USD/THB", time.Minute)
+	cache.Delete("USD/THB")
+	cache.Set("USD/JPY", 151.2, time.Second)
+	clock.Advance(time.Hour)
+	clock.BlockUntil(1)
+
+	want := []string{"update USD/THB=36.6", "delete USD/THB=36.6", "set USD/JPY=151.2", "expire USD/JPY=151.2"}
+	if got := drainEvents(usd); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("USD/* events = %q, want %q", got, want)
+	}
+	want = []string{"set USD/THB=36.5", "update USD/THB=36.6", "set EUR/USD=1.08", "delete USD/THB=36.6",
+		"set USD/JPY=151.2", "expire USD/JPY=151.2", "expire EUR/USD=1.08"}
+	if got := drainEvents(all); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("all events = %q, want %q", got, want)
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-usd; ok {
+		t.Fatal("channel still open after cancel")
+	}
+	cache.Set("USD/THB", 36.5)
+	if got := drainEvents(all); len(got) != 1 {
+		t.Fatalf("remaining subscriber got %q, want one event", got)
+	}
+}
+
+func TestSubscribeCapacityAndOverwrittenExpiredEntry(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL: time.Minute,
+		MaxEntries: 1,
+		Clock:      clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+	events, cancel, err := cache.Subscribe(PrefixFilter("USD/"))
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer cancel()
+
+	cache.Set("USD/THB", 36.5, time.Second)
+	clock.Set(time.Unix(2, 0))
+	// The janitor has not run, so the overwrite finds the expired entry.
+	cache.Set("USD/THB", 36.6)
+	cache.Set("USD/JPY", 151.2)
+
+	want := []string{"set USD/THB=36.5", "expire USD/THB=36.5", "set USD/THB=36.6", "evict USD/THB=36.6", "set USD/JPY=151.2"}
+	if got := drainEvents(events); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+}
+
+func TestSubscribeOverflow(t *testing.T) {
+	tests := []struct {
+		overflow OverflowPolicy
+		want     []string
+	}{
+		{OverflowDropNewest, []string{"set A=1", "set B=2"}},
+		{OverflowDropOldest, []string{"set C=3", "set D=4"}},
+	}
+	for _, tt := range tests {
+		cache, _ := newTestCache(t)
+		events, cancel, err := cache.Subscribe(nil, SubscribeOptions{Buffer: 2, Overflow: tt.overflow})
+		if err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		defer cancel()
+
+		// Nobody reads while the rates change; the writer must not block.
+		for i, key := range []string{"A", "B", "C", "D"} {
+			cache.Set(key, float64(i+1))
+		}
+		if got := drainEvents(events); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("policy %d: events = %q, want %q", tt.overflow, got, tt.want)
+		}
+		if dropped := cache.Stats().DroppedEvents; dropped != 2 {
+			t.Errorf("policy %d: DroppedEvents = %d, want 2", tt.overflow, dropped)
+		}
+	}
+}
+
+func TestSubscribeBlock(t *testing.T) {
+	cache, _ := newTestCache(t)
+	events, cancel, err := cache.Subscribe(nil, SubscribeOptions{Buffer: 1, Overflow: OverflowBlock})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	cache.Set("A", 1)
+	done := make(chan struct{})
+	go func() {
+		cache.Set("B", 2)
+		cache.Set("C", 3)
+		close(done)
+	}()
+
+	// The writer waits for room instead of dropping events.
+	for _, want := range []string{"A", "B"} {
+		if e := <-events; e.Key != want {
+			t.Fatalf("event for %s, want %s", e.Key, want)
+		}
+	}
+	// Cancelling releases a writer still blocked on the subscriber.
+	cancel()
+	<-done
+	if dropped := cache.Stats().DroppedEvents; dropped != 0 {
+		t.Fatalf("DroppedEvents = %d, want 0", dropped)
+	}
+}
+
+func TestSubscribeInvalidOptions(t *testing.T) {
+	cache, _ := newTestCache(t)
+	for _, opts := range []SubscribeOptions{{Buffer: -1}, {Overflow: OverflowBlock + 1}} {
+		if _, _, err := cache.Subscribe(nil, opts); err == nil {
+			t.Errorf("Subscribe(%+v) succeeded, want an error", opts)
+		}
+	}
+}
+
+func TestShardedSubscribe(t *testing.T) {
+	cache, err := NewShardedTTLCache[string, float64](4, Config{DefaultTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewShardedTTLCache: %v", err)
+	}
+	defer cache.StopJanitor()
+	events, cancel, err := cache.Subscribe(GlobFilter("*/USD"))
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	for _, pair := range []string{"EUR/USD", "GBP/USD", "USD/THB", "AUD/USD"} {
+		cache.Set(pair, 1)
+	}
+	seen := map[string]bool{}
+	for range 3 {
+		seen[(<-events).Key] = true
+	}
+	if !seen["EUR/USD"] || !seen["GBP/USD"] || !seen["AUD/USD"] {
+		t.Fatalf("events for %v, want the three */USD pairs", seen)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("channel still open after cancel")
+	}
+}
@@ -0,0 +1,465 @@
+package fxcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TierMode selects how a Tier writes to its Store. Reads are always
+// read-through: a local miss is loaded from the store.
+type TierMode int
+
+const (
+	// TierWriteThrough writes to the store before the local cache, so a
+	// write returns once it is shared. It is the default.
+	TierWriteThrough TierMode = iota
+	// TierWriteBehind writes to the local cache and queues the write to the
+	// store, which a background goroutine performs. Repeated writes of a key
+	// are coalesced. Writes still queued are lost if the process dies.
+	TierWriteBehind
+	// TierWriteAround writes only to the store and drops the local copy, so
+	// the value is read through from the store when it is next needed.
+	TierWriteAround
+)
+
+// String returns the mode name.
+func (m TierMode) String() string {
+	switch m {
+	case TierWriteThrough:
+		return "write-through"
+	case TierWriteBehind:
+		return "write-behind"
+	case TierWriteAround:
+		return "write-around"
+	default:
+		return fmt.Sprintf("TierMode(%d)", int(m))
+	}
+}
+
+// Invalidation tells the other nodes that the value of Key changed, so they
+// drop their local copy. Origin identifies the node that sent it.
+type Invalidation[K comparable] struct {
+	Origin string `json:"origin"`
+	Key    K      `json:"key"`
+}
+
+// Invalidator carries invalidations between the Tiers of several nodes, e.g.
+// over Redis pub/sub. Delivery may be asynchronous.
+type Invalidator[K comparable] interface {
+	// Publish sends msg to every subscriber, including the sender's own.
+	Publish(ctx context.Context, msg Invalidation[K]) error
+	// Subscribe calls fn with every published message until cancel is
+	// called.
+	Subscribe(fn func(Invalidation[K])) (cancel func(), err error)
+}
+
+// InvalidationBus is an Invalidator within one process. It delivers each
+// message synchronously, before Publish returns.
+type InvalidationBus[K comparable] struct {
+	mu   sync.RWMutex
+	subs map[int]func(Invalidation[K])
+	next int
+}
+
+// NewInvalidationBus returns a bus without subscribers.
+func NewInvalidationBus[K comparable]() *InvalidationBus[K] {
+	return &InvalidationBus[K]{subs: make(map[int]func(Invalidation[K]))}
+}
+
+// Publish implements Invalidator.
+func (b *InvalidationBus[K]) Publish(_ context.Context, msg Invalidation[K]) error {
+	b.mu.RLock()
+	subs := make([]func(Invalidation[K]), 0, len(b.subs))
+	for _, fn := range b.subs {
+		subs = append(subs, fn)
+	}
+	b.mu.RUnlock()
+	for _, fn := range subs {
+		fn(msg)
+	}
+	return nil
+}
+
+// Subscribe implements Invalidator.
+func (b *InvalidationBus[K]) Subscribe(fn func(Invalidation[K])) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.subs[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}, nil
+}
+
+// TierConfig holds the settings used by NewTier. Zero values fall back to the
+// defaults.
+type TierConfig[K comparable, V any] struct {
+	// Store is the shared store behind the local cache. It is required.
+	Store Store[K, V]
+	// Mode chooses how writes reach the store.
+	Mode TierMode
+	// LocalTTL caps how long a value is kept in the local cache, so a node
+	// that missed an invalidation serves it for at most this long. Zero keeps
+	// local copies until the value expires in the store.
+	LocalTTL time.Duration
+	// Invalidator, if set, carries invalidations between nodes: every write
+	// is published once it reached the store, and the other nodes drop their
+	// local copy of the key.
+	Invalidator Invalidator[K]
+	// NodeID identifies this node in invalidations, so it ignores its own.
+	// It defaults to a random ID.
+	NodeID string
+	// OnError receives the errors of write-behind writes and of publishing
+	// their invalidations. It defaults to logging them.
+	OnError func(error)
+}
+
+// tierWrite is a write-behind write waiting for the store. seq orders the
+// writes of a tier.
+type tierWrite[V any] struct {
+	value     V
+	expiresAt time.Time
+	deleted   bool
+	seq       uint64
+}
+
+// localWrites tracks the write-behind writes of a key that are being applied
+// to the local cache: latest is the newest of them and inflight their number.
+type localWrites[V any] struct {
+	latest   tierWrite[V]
+	inflight int
+}
+
+// Tier is a two-level cache: a local TTLCache (L1) in front of a slower
+// Store shared by several nodes (L2). It is safe for concurrent use.
+type Tier[K comparable, V any] struct {
+	local       *TTLCache[K, V]
+	store       Store[K, V]
+	mode        TierMode
+	localTTL    time.Duration
+	invalidator Invalidator[K]
+	nodeID      string
+	onError     func(error)
+	unsubscribe func()
+
+	// Write-behind state, guarded by mu. pending holds the queued writes and
+	// writing the ones the writer is performing; drained is closed and
+	// replaced whenever the writer finishes a batch. applying holds the keys
+	// whose writes are being applied locally, outside mu.
+	mu       sync.Mutex
+	seq      uint64
+	pending  map[K]tierWrite[V]
+	writing  map[K]tierWrite[V]
+	applying map[K]*localWrites[V]
+	drained  chan struct{}
+	wake     chan struct{}
+	closed   bool
+
+	// ctx is passed to the writer's store calls; Close cancels it to stop
+	// the writer.
+	ctx       context.Context
+	cancel    context.CancelFunc
+	writer    sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewTier returns a Tier that keeps local in front of cfg.Store. Call Close
+// to write out queued writes and stop listening for invalidations; it does
+// not stop local.
+func NewTier[K comparable, V any](local *TTLCache[K, V], cfg TierConfig[K, V]) (*Tier[K, V], error) {
+	if cfg.Store == nil {
+		return nil, errors.New("tier needs a store")
+	}
+	if cfg.Mode < TierWriteThrough || cfg.Mode > TierWriteAround {
+		return nil, fmt.Errorf("unknown tier mode %d", cfg.Mode)
+	}
+	if cfg.LocalTTL < 0 {
+		return nil, fmt.Errorf("tier local TTL must not be negative")
+	}
+	if cfg.NodeID == "" {
+		var id [8]byte
+		rand.Read(id[:])
+		cfg.NodeID = hex.EncodeToString(id[:])
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = logError
+	}
+
+	t := &Tier[K, V]{
+		local:       local,
+		store:       cfg.Store,
+		mode:        cfg.Mode,
+		localTTL:    cfg.LocalTTL,
+		invalidator: cfg.Invalidator,
+		nodeID:      cfg.NodeID,
+		onError:     cfg.OnError,
+		pending:     make(map[K]tierWrite[V]),
+		applying:    make(map[K]*localWrites[V]),
+		drained:     make(chan struct{}),
+		wake:        make(chan struct{}, 1),
+	}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	if t.invalidator != nil {
+		cancel, err := t.invalidator.Subscribe(t.invalidate)
+		if err != nil {
+			return nil, fmt.Errorf("subscribe to invalidations: %w", err)
+		}
+		t.unsubscribe = cancel
+	}
+	if t.mode == TierWriteBehind {
+		t.writer.Add(1)
+		go t.writeBehind()
+	}
+	return t, nil
+}
+
+// invalidate drops the local copy of a key written by another node.
+func (t *Tier[K, V]) invalidate(msg Invalidation[K]) {
+	if msg.Origin != t.nodeID {
+		t.local.Delete(msg.Key)
+	}
+}
+
+// Get returns the value of key from the local cache, or else from the store,
+// caching it locally until it expires in the store (or LocalTTL has passed).
+// It returns an error wrapping ErrNotFound if the key has no live value.
+func (t *Tier[K, V]) Get(ctx context.Context, key K) (V, error) {
+	if value, ok := t.local.Get(key); ok {
+		return value, nil
+	}
+	var zero V
+	if w, ok := t.queued(key); ok {
+		if w.deleted || !t.local.clock.Now().Before(w.expiresAt) {
+			return zero, fmt.Errorf("key %v: %w", key, ErrNotFound)
+		}
+		return w.value, nil
+	}
+
+	value, expiresAt, err := t.store.Get(ctx, key)
+	if err != nil {
+		return zero, err
+	}
+	t.storeLocal(key, value, expiresAt)
+	return value, nil
+}
+
+// queued returns the write-behind write of key that has not reached the
+// store yet, which is newer than the store's value.
+func (t *Tier[K, V]) queued(key K) (tierWrite[V], bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if w, ok := t.pending[key]; ok {
+		return w, true
+	}
+	w, ok := t.writing[key]
+	return w, ok
+}
+
+// storeLocal caches a value locally until expiresAt, capped by LocalTTL.
+func (t *Tier[K, V]) storeLocal(key K, value V, expiresAt time.Time) {
+	if t.localTTL > 0 {
+		if limit := t.local.clock.Now().Add(t.localTTL); limit.Before(expiresAt) {
+			expiresAt = limit
+		}
+	}
+	t.local.SetUntil(key, value, expiresAt)
+}
+
+// Set stores the value of key with the optional ttl, or the local cache's
+// default TTL, as chosen by the tier's mode.
+func (t *Tier[K, V]) Set(ctx context.Context, key K, value V, ttl ...time.Duration) error {
+	d := t.local.DefaultTTL()
+	if len(ttl) > 0 && ttl[0] > 0 {
+		d = ttl[0]
+	}
+	return t.write(ctx, key, tierWrite[V]{value: value, expiresAt: t.local.clock.Now().Add(d)})
+}
+
+// Delete removes key from the store and the local cache.
+func (t *Tier[K, V]) Delete(ctx context.Context, key K) error {
+	return t.write(ctx, key, tierWrite[V]{deleted: true})
+}
+
+func (t *Tier[K, V]) write(ctx context.Context, key K, w tierWrite[V]) error {
+	if t.mode == TierWriteBehind {
+		t.mu.Lock()
+		if t.closed {
+			t.mu.Unlock()
+			return ErrClosed
+		}
+		t.seq++
+		w.seq = t.seq
+		t.pending[key] = w
+		a := t.applying[key]
+		if a == nil {
+			a = &localWrites[V]{}
+			t.applying[key] = a
+		}
+		a.latest = w
+		a.inflight++
+		t.mu.Unlock()
+		select {
+		case t.wake <- struct{}{}:
+		default:
+		}
+		// The local cache runs its callbacks, so it is written without mu.
+		t.applyLatest(key, w)
+		return nil
+	}
+
+	t.mu.Lock()
+	closed := t.closed
+	t.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	if err := t.writeStore(ctx, key, w); err != nil {
+		return err
+	}
+	if t.mode == TierWriteAround {
+		t.local.Delete(key)
+	} else {
+		t.apply(key, w)
+	}
+	return t.publish(ctx, key)
+}
+
+// apply makes a write in the local cache.
+func (t *Tier[K, V]) apply(key K, w tierWrite[V]) {
+	if w.deleted {
+		t.local.Delete(key)
+		return
+	}
+	t.storeLocal(key, w.value, w.expiresAt)
+}
+
+// applyLatest applies the write-behind write w locally. A newer write of the
+// key may have been applied before w; then it is applied again, so the local
+// cache ends with the last write queued, as the store does.
+func (t *Tier[K, V]) applyLatest(key K, w tierWrite[V]) {
+	for {
+		t.apply(key, w)
+		t.mu.Lock()
+		a := t.applying[key]
+		if a.latest.seq == w.seq {
+			if a.inflight--; a.inflight == 0 {
+				delete(t.applying, key)
+			}
+			t.mu.Unlock()
+			return
+		}
+		w = a.latest
+		t.mu.Unlock()
+	}
+}
+
+func (t *Tier[K, V]) writeStore(ctx context.Context, key K, w tierWrite[V]) error {
+	if w.deleted {
+		if err := t.store.Delete(ctx, key); err != nil {
+			return fmt.Errorf("delete %v from store: %w", key, err)
+		}
+		return nil
+	}
+	if err := t.store.Set(ctx, key, w.value, w.expiresAt); err != nil {
+		return fmt.Errorf("write %v to store: %w", key, err)
+	}
+	return nil
+}
+
+func (t *Tier[K, V]) publish(ctx context.Context, key K) error {
+	if t.invalidator == nil {
+		return nil
+	}
+	if err := t.invalidator.Publish(ctx, Invalidation[K]{Origin: t.nodeID, Key: key}); err != nil {
+		return fmt.Errorf("publish invalidation of %v: %w", key, err)
+	}
+	return nil
+}
+
+// writeBehind performs the queued writes until Close. A write that fails,
+// or that Close cancels, is reported to OnError and dropped.
+func (t *Tier[K, V]) writeBehind() {
+	defer t.writer.Done()
+	for {
+		select {
+		case <-t.wake:
+		case <-t.ctx.Done():
+			return
+		}
+		for {
+			t.mu.Lock()
+			if len(t.pending) == 0 {
+				t.mu.Unlock()
+				break
+			}
+			t.writing, t.pending = t.pending, make(map[K]tierWrite[V])
+			batch := t.writing
+			t.mu.Unlock()
+
+			for key, w := range batch {
+				err := t.writeStore(t.ctx, key, w)
+				if err == nil {
+					err = t.publish(t.ctx, key)
+				}
+				if err != nil {
+					t.onError(err)
+				}
+			}
+
+			t.mu.Lock()
+			t.writing = nil
+			close(t.drained)
+			t.drained = make(chan struct{})
+			t.mu.Unlock()
+		}
+	}
+}
+
+// Flush waits until every write-behind write queued so far has been written
+// to the store, or ctx is done. It returns at once in the other modes.
+func (t *Tier[K, V]) Flush(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		if len(t.pending) == 0 && t.writing == nil {
+			t.mu.Unlock()
+			return nil
+		}
+		drained := t.drained
+		t.mu.Unlock()
+
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close flushes the queued writes, waiting until ctx is done at most, and
+// stops the writer and the invalidation subscription. If ctx ends first, the
+// store calls in progress are cancelled and the writes still queued are
+// dropped and reported to OnError. Writes after Close return ErrClosed;
+// reads keep working. It is safe to call more than once.
+func (t *Tier[K, V]) Close(ctx context.Context) error {
+	var err error
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		t.closed = true
+		t.mu.Unlock()
+		err = t.Flush(ctx)
+		t.cancel()
+		t.writer.Wait()
+		if t.unsubscribe != nil {
+			t.unsubscribe()
+		}
+	})
+	return err
+}
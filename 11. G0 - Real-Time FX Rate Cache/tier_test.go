@@ -0,0 +1,342 @@
+package fxcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTierNode returns a tier over store whose local cache uses clock, as one
+// node of a cluster sharing store and bus.
+func newTierNode(t *testing.T, clock Clock, cfg TierConfig[string, float64]) (*Tier[string, float64], *FXRateCache) {
+	t.Helper()
+	local, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Hour,
+		JanitorInterval: time.Hour,
+		Clock:           clock,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	tier, err := NewTier(local, cfg)
+	if err != nil {
+		t.Fatalf("NewTier: %v", err)
+	}
+	t.Cleanup(func() {
+		tier.Close(context.Background())
+		local.StopJanitor()
+	})
+	return tier, local
+}
+
+func TestTierWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	store := NewMemoryStore[string, float64](clock)
+	tier, local := newTierNode(t, clock, TierConfig[string, float64]{Store: store})
+
+	if err := tier.Set(ctx, "USD/THB", 36.5, time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if value, expiresAt, err := store.Get(ctx, "USD/THB"); err != nil || value != 36.5 || !expiresAt.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("store has %v, %v, %v; want 36.5 for a minute", value, expiresAt, err)
+	}
+	if value, ok := local.Get("USD/THB"); !ok || value != 36.5 {
+		t.Errorf("local cache has %v, %v; want 36.5, true", value, ok)
+	}
+
+	if err := tier.Delete(ctx, "USD/THB"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := tier.Get(ctx, "USD/THB"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTierReadsThroughWithStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	store := NewMemoryStore[string, float64](clock)
+	store.Set(ctx, "USD/THB", 36.5, clock.Now().Add(10*time.Minute))
+	store.Set(ctx, "EUR/USD", 1.08, clock.Now().Add(10*time.Second))
+	tier, local := newTierNode(t, clock, TierConfig[string, float64]{Store: store, LocalTTL: time.Minute})
+
+	for key, want := range map[string]time.Duration{
+		// Local copies live until the store expires them, or LocalTTL.
+		"USD/THB": time.Minute,
+		"EUR/USD": 10 * time.Second,
+	} {
+		if _, err := tier.Get(ctx, key); err != nil {
+			t.Fatalf("Get(%s): %v", key, err)
+		}
+		if _, ttl, ok := local.GetWithExpiry(key); !ok || ttl != want {
+			t.Errorf("local %s TTL = %v, %v; want %v", key, ttl, ok, want)
+		}
+	}
+
+	// A value changed in the store is seen once the local copy expires.
+	store.Set(ctx, "USD/THB", 36.6, clock.Now().Add(10*time.Minute))
+	if value, _ := tier.Get(ctx, "USD/THB"); value != 36.5 {
+		t.Errorf("Get = %v, want the local copy 36.5", value)
+	}
+	clock.Advance(time.Minute)
+	if value, _ := tier.Get(ctx, "USD/THB"); value != 36.6 {
+		t.Errorf("Get = %v after LocalTTL, want 36.6", value)
+	}
+}
+
+func TestTierWriteAroundMode(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	store := NewMemoryStore[string, float64](clock)
+	tier, local := newTierNode(t, clock, TierConfig[string, float64]{Store: store, Mode: TierWriteAround})
+
+	tier.Set(ctx, "USD/THB", 36.5)
+	if local.Has("USD/THB") {
+		t.Error("write-around Set cached the value locally")
+	}
+	if value, err := tier.Get(ctx, "USD/THB"); err != nil || value != 36.5 {
+		t.Errorf("Get = %v, %v; want 36.5, nil", value, err)
+	}
+	if !local.Has("USD/THB") {
+		t.Error("Get did not cache the value read through")
+	}
+	tier.Set(ctx, "USD/THB", 36.6)
+	if local.Has("USD/THB") {
+		t.Error("write-around Set kept the old local copy")
+	}
+}
+
+func TestTierWriteBehind(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	store := &blockingStore{MemoryStore: NewMemoryStore[string, float64](clock), release: make(chan struct{})}
+	tier, _ := newTierNode(t, clock, TierConfig[string, float64]{Store: store, Mode: TierWriteBehind})
+
+	tier.Set(ctx, "USD/THB", 36.5)
+	tier.Set(ctx, "USD/THB", 36.6)
+	tier.Set(ctx, "EUR/USD", 1.08)
+	tier.Delete(ctx, "EUR/USD")
+
+	// The writes are visible locally before they reach the store.
+	if value, err := tier.Get(ctx, "USD/THB"); err != nil || value != 36.6 {
+		t.Errorf("Get = %v, %v; want 36.6, nil", value, err)
+	}
+	if _, err := tier.Get(ctx, "EUR/USD"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a deleted key: err = %v, want ErrNotFound", err)
+	}
+
+	close(store.release)
+	if err := tier.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if value, _, err := store.Get(ctx, "USD/THB"); err != nil || value != 36.6 {
+		t.Errorf("store has %v, %v; want 36.6, nil", value, err)
+	}
+	if _, _, err := store.Get(ctx, "EUR/USD"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("store has EUR/USD: err = %v, want ErrNotFound", err)
+	}
+
+	tier.Set(ctx, "USD/JPY", 151.2)
+	if err := tier.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, _, err := store.Get(ctx, "USD/JPY"); err != nil {
+		t.Errorf("Close did not write out the queued write: %v", err)
+	}
+	if err := tier.Set(ctx, "USD/JPY", 151.3); !errors.Is(err, ErrClosed) {
+		t.Errorf("Set after Close: err = %v, want ErrClosed", err)
+	}
+}
+
+// blockingStore is a MemoryStore whose writes wait until release is closed
+// or their context is done.
+type blockingStore struct {
+	*MemoryStore[string, float64]
+	release chan struct{}
+}
+
+func (s *blockingStore) Set(ctx context.Context, key string, value float64, expiresAt time.Time) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.MemoryStore.Set(ctx, key, value, expiresAt)
+}
+
+func (s *blockingStore) Delete(ctx context.Context, key string) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.MemoryStore.Delete(ctx, key)
+}
+
+func TestTierWriteBehindCallbacksMayUseTier(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	tier, local := newTierNode(t, clock, TierConfig[string, float64]{Store: NewMemoryStore[string, float64](clock), Mode: TierWriteBehind})
+
+	// A local miss looks at the write-behind queue, under the tier's lock.
+	seen := make(chan error, 1)
+	local.OnSet(func(string, float64, time.Time) {
+		_, err := tier.Get(ctx, "EUR/USD")
+		seen <- err
+	})
+	tier.Set(ctx, "USD/THB", 36.5)
+	if err := <-seen; !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get from OnSet: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTierWriteBehindKeepsLastWrite(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	store := NewMemoryStore[string, float64](clock)
+	tier, local := newTierNode(t, clock, TierConfig[string, float64]{Store: store, Mode: TierWriteBehind})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 200 {
+				tier.Set(ctx, "USD/THB", float64(i*1000+j))
+			}
+		}()
+	}
+	wg.Wait()
+	if err := tier.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	want, _, _ := store.Get(ctx, "USD/THB")
+	if got, _ := local.Get("USD/THB"); got != want {
+		t.Fatalf("local copy = %v, store = %v; want the same last write", got, want)
+	}
+}
+
+func TestTierCloseCancelsStuckWrites(t *testing.T) {
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	store := &blockingStore{MemoryStore: NewMemoryStore[string, float64](clock), release: make(chan struct{})}
+	var mu sync.Mutex
+	var errs []error
+	tier, _ := newTierNode(t, clock, TierConfig[string, float64]{
+		Store: store,
+		Mode:  TierWriteBehind,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+
+	tier.Set(context.Background(), "USD/THB", 36.5)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tier.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close = %v, want context.DeadlineExceeded", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Fatalf("OnError got %v, want the cancelled write", errs)
+	}
+}
+
+func TestTierInvalidatesOtherNodes(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range []TierMode{TierWriteThrough, TierWriteBehind, TierWriteAround} {
+		t.Run(mode.String(), func(t *testing.T) {
+			clock := NewManualClock(time.Unix(1_700_000_000, 0))
+			store, err := NewFileStore[string, float64](t.TempDir(), clock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bus := NewInvalidationBus[string]()
+			cfg := TierConfig[string, float64]{Store: store, Mode: mode, Invalidator: bus}
+			a, localA := newTierNode(t, clock, cfg)
+			b, localB := newTierNode(t, clock, cfg)
+
+			a.Set(ctx, "USD/THB", 36.5)
+			a.Flush(ctx)
+			if value, err := b.Get(ctx, "USD/THB"); err != nil || value != 36.5 {
+				t.Fatalf("b.Get = %v, %v; want 36.5, nil", value, err)
+			}
+
+			// b's copy is purged by a's next write, not served stale.
+			a.Set(ctx, "USD/THB", 36.6)
+			a.Flush(ctx)
+			if localB.Has("USD/THB") {
+				t.Error("b kept its local copy after a wrote the key")
+			}
+			if value, _ := b.Get(ctx, "USD/THB"); value != 36.6 {
+				t.Errorf("b.Get = %v, want 36.6", value)
+			}
+			if mode != TierWriteAround && !localA.Has("USD/THB") {
+				t.Error("a dropped its own copy on its own invalidation")
+			}
+
+			b.Delete(ctx, "USD/THB")
+			b.Flush(ctx)
+			if _, err := a.Get(ctx, "USD/THB"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("a.Get after b deleted: err = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestTierWriteBehindReportsErrors(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	var mu sync.Mutex
+	var errs []error
+	tier, _ := newTierNode(t, clock, TierConfig[string, float64]{
+		Store: failingStore{},
+		Mode:  TierWriteBehind,
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+
+	tier.Set(ctx, "USD/THB", 36.5)
+	tier.Flush(ctx)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 {
+		t.Errorf("OnError got %v, want one error", errs)
+	}
+}
+
+// failingStore is a Store whose writes always fail.
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) (float64, time.Time, error) {
+	return 0, time.Time{}, ErrNotFound
+}
+
+func (failingStore) Set(context.Context, string, float64, time.Time) error {
+	return errors.New("store unavailable")
+}
+
+func (failingStore) Delete(context.Context, string) error {
+	return errors.New("store unavailable")
+}
+
+func TestNewTierValidatesConfig(t *testing.T) {
+	cache, _ := newTestCache(t)
+	for _, cfg := range []TierConfig[string, float64]{
+		{},
+		{Store: failingStore{}, Mode: TierMode(7)},
+		{Store: failingStore{}, LocalTTL: -time.Second},
+	} {
+		if _, err := NewTier(cache, cfg); err == nil {
+			t.Errorf("NewTier(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
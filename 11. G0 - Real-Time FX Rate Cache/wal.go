@@ -0,0 +1,325 @@
+package fxcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WALSyncPolicy controls when appends to the write-ahead log are flushed to
+// stable storage with fsync.
+type WALSyncPolicy int
+
+const (
+	// WALSyncAlways fsyncs after every record. Nothing acknowledged is lost
+	// on a crash, at the cost of one fsync per write. It is the default.
+	WALSyncAlways WALSyncPolicy = iota
+	// WALSyncInterval fsyncs in the background every Config.WALSyncInterval.
+	// A crash loses at most that much history.
+	WALSyncInterval
+	// WALSyncNever leaves flushing to the operating system.
+	WALSyncNever
+)
+
+// DefaultWALSyncInterval is used with WALSyncInterval when
+// Config.WALSyncInterval is zero.
+const DefaultWALSyncInterval = time.Second
+
+// walHeader starts every log file; its last byte is the format version.
+var walHeader = []byte("TTLWAL\x00\x01")
+
+// walCRC is the checksum table for records.
+var walCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// maxWALRecord bounds the payload of one record. A larger length in a frame
+// can only come from a torn or corrupt write, and is not allocated.
+const maxWALRecord = 16 << 20
+
+const (
+	walOpSet = "set"
+	walOpDel = "del"
+)
+
+// walRecord is the JSON payload of one log record.
+type walRecord[K comparable, V any] struct {
+	Op        string `json:"op"`
+	Key       K      `json:"key"`
+	Value     V      `json:"value"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
+// wal is an append-only log file. Each record is framed as a 4-byte
+// big-endian payload length, a 4-byte CRC-32C of the payload, and the
+// payload. Appends are serialized by the cache lock; mu only orders them
+// against the background sync.
+type wal struct {
+	mu     sync.Mutex
+	f      *os.File
+	policy WALSyncPolicy
+	dirty  bool
+}
+
+// openWAL opens or creates the log at path. It calls apply for each intact
+// record and truncates a torn or corrupt tail left by a crash, so that new
+// records are appended right after the last good one.
+func openWAL(path string, policy WALSyncPolicy, apply func(payload []byte) error) (*wal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	good, err := replayWAL(f, apply)
+	if err == nil {
+		err = f.Truncate(good)
+	}
+	if err == nil && good == 0 {
+		// The replay moved the file offset, so write at 0 explicitly.
+		_, err = f.WriteAt(walHeader, 0)
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekEnd)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &wal{f: f, policy: policy}, nil
+}
+
+// replayWAL reads records from the start of f and returns the offset just
+// past the last intact one. A short read or a checksum mismatch ends the
+// replay, as does a length over maxWALRecord; anything after that offset is
+// the torn tail of an interrupted write.
+func replayWAL(f *os.File, apply func(payload []byte) error) (int64, error) {
+	r := bufio.NewReader(f)
+	header := make([]byte, len(walHeader))
+	if _, err := io.ReadFull(r, header); err != nil {
+		// An empty file, or a crash while writing the header.
+		return 0, nil
+	}
+	if !bytes.Equal(header, walHeader) {
+		return 0, fmt.Errorf("not a TTLCache write-ahead log, or unsupported version")
+	}
+
+	good := int64(len(walHeader))
+	var frame [8]byte
+	for {
+		if _, err := io.ReadFull(r, frame[:]); err != nil {
+			return good, nil
+		}
+		size := binary.BigEndian.Uint32(frame[0:4])
+		sum := binary.BigEndian.Uint32(frame[4:8])
+		if size > maxWALRecord {
+			return good, nil
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return good, nil
+		}
+		if crc32.Checksum(payload, walCRC) != sum {
+			return good, nil
+		}
+		if err := apply(payload); err != nil {
+			return 0, fmt.Errorf("replay record at offset %d: %w", good, err)
+		}
+		good += int64(len(frame) + len(payload))
+	}
+}
+
+// walFrame returns payload framed as a log record.
+func walFrame(payload []byte) []byte {
+	buf := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, walCRC))
+	copy(buf[8:], payload)
+	return buf
+}
+
+// append writes one framed record and syncs it if the policy says so. It
+// rejects payloads over maxWALRecord, which replay would drop.
+func (w *wal) append(payload []byte) error {
+	if len(payload) > maxWALRecord {
+		return fmt.Errorf("record of %d bytes exceeds the %d-byte limit", len(payload), maxWALRecord)
+	}
+	buf := walFrame(payload)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.f.Write(buf); err != nil {
+		return err
+	}
+	if w.policy == WALSyncAlways {
+		return w.f.Sync()
+	}
+	w.dirty = true
+	return nil
+}
+
+// sync flushes the log if anything was appended since the last sync.
+func (w *wal) sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.f.Sync()
+}
+
+// reset truncates the log back to its header.
+func (w *wal) reset() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.f.Truncate(int64(len(walHeader))); err != nil {
+		return err
+	}
+	if _, err := w.f.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	w.dirty = false
+	return w.f.Sync()
+}
+
+// close syncs and closes the log file.
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return errors.Join(w.f.Sync(), w.f.Close())
+}
+
+// walSnapshotPath is where CompactWAL stores the snapshot that replaces the
+// compacted records.
+func walSnapshotPath(walPath string) string {
+	return walPath + ".snapshot"
+}
+
+// openWAL restores the cache from the WAL's compacted snapshot and log, then
+// starts logging to it. It is called while the cache is being created.
+func (c *TTLCache[K, V]) openWAL(path string, policy WALSyncPolicy, syncInterval time.Duration) error {
+	c.walPath = path
+	if err := c.LoadSnapshotFile(walSnapshotPath(path)); err != nil {
+		return fmt.Errorf("load compacted snapshot: %w", err)
+	}
+
+	w, err := openWAL(path, policy, c.replayRecord)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.wal = w
+	c.mu.Unlock()
+
+	if policy == WALSyncInterval {
+		c.startWALSync(syncInterval)
+	}
+	return nil
+}
+
+// replayRecord applies one logged operation.
+func (c *TTLCache[K, V]) replayRecord(payload []byte) error {
+	var rec walRecord[K, V]
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.unlockAndNotify()
+	now := c.clock.Now().UnixMilli()
+	switch rec.Op {
+	case walOpSet:
+		if now < rec.ExpiresAt {
+			c.storeLocked(rec.Key, rec.Value, now, rec.ExpiresAt)
+		} else {
+			c.removeLocked(rec.Key, ReasonExpired)
+		}
+	case walOpDel:
+		c.removeLocked(rec.Key, ReasonDeleted)
+	default:
+		return fmt.Errorf("unknown operation %q", rec.Op)
+	}
+	return nil
+}
+
+// logSetLocked appends a set record for item. It must be called with c.mu held.
+func (c *TTLCache[K, V]) logSetLocked(item *cacheItem[K, V]) {
+	if c.wal != nil {
+		c.logLocked(walRecord[K, V]{Op: walOpSet, Key: item.key, Value: item.Value, ExpiresAt: item.ExpiresAt})
+	}
+}
+
+// logDeleteLocked appends a delete record for key. It must be called with
+// c.mu held.
+func (c *TTLCache[K, V]) logDeleteLocked(key K) {
+	if c.wal != nil {
+		c.logLocked(walRecord[K, V]{Op: walOpDel, Key: key})
+	}
+}
+
+func (c *TTLCache[K, V]) logLocked(rec walRecord[K, V]) {
+	payload, err := json.Marshal(rec)
+	if err == nil {
+		err = c.wal.append(payload)
+	}
+	if err != nil {
+		// Reported by unlockAndNotify, since OnError may use the cache.
+		c.failed = append(c.failed, fmt.Errorf("write-ahead log %s: %w", c.walPath, err))
+	}
+}
+
+// CompactWAL replaces the write-ahead log with a snapshot of the live entries
+// and truncates the log. Writers are blocked while it runs. It returns an
+// error if the cache was created without Config.WALPath, and ErrClosed after
+// Close.
+func (c *TTLCache[K, V]) CompactWAL() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	if c.wal == nil {
+		return fmt.Errorf("write-ahead log is not enabled")
+	}
+	now := c.clock.Now().UnixMilli()
+	snap := snapshot[K, V]{Version: SnapshotVersion, SavedAt: now, Entries: c.snapshotEntriesLocked(now)}
+	err := writeFileAtomic(walSnapshotPath(c.walPath), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(snap)
+	})
+	if err != nil {
+		return fmt.Errorf("write compacted snapshot: %w", err)
+	}
+	return c.wal.reset()
+}
+
+// startWALSync fsyncs the log every interval until Close.
+func (c *TTLCache[K, V]) startWALSync(interval time.Duration) {
+	timer := c.clock.NewTimer(interval)
+
+	c.workers.Add(1)
+	go func() {
+		defer c.workers.Done()
+		for {
+			select {
+			case <-timer.C():
+				if err := c.wal.sync(); err != nil {
+					c.onError(fmt.Errorf("sync write-ahead log %s: %w", c.walPath, err))
+				}
+				timer.Reset(interval)
+			case <-c.stopWorkers:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
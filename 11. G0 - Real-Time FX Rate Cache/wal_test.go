@@ -0,0 +1,231 @@
+package fxcache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openWALCache creates a cache logging to path on clock. Each call simulates
+// a process start; earlier caches are never stopped, as after a crash, and
+// are only cleaned up when the test ends.
+func openWALCache(t *testing.T, path string, clock *ManualClock) *FXRateCache {
+	t.Helper()
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Hour,
+		JanitorInterval: time.Hour,
+		Clock:           clock,
+		WALPath:         path,
+		OnError:         func(err error) { t.Errorf("wal: %v", err) },
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	// Close, so the log is closed before the directory is removed.
+	t.Cleanup(func() { cache.Close(context.Background()) })
+	return cache
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestWALReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fx.wal")
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+
+	first := openWALCache(t, path, clock)
+	first.Set("USD/THB", 36.5)
+	first.Set("EUR/USD", 1.08)
+	first.Set("USD/THB", 36.6)
+	first.Set("GBP/USD", 1.27, time.Second)
+	first.Set("USD/JPY", 151.2)
+	first.Delete("USD/JPY")
+
+	clock.Advance(2 * time.Second)
+	second := openWALCache(t, path, clock)
+
+	if val, ok := second.Get("USD/THB"); !ok || val != 36.6 {
+		t.Fatalf("Get(USD/THB) = (%v, %v), want (36.6, true)", val, ok)
+	}
+	if val, ok := second.Get("EUR/USD"); !ok || val != 1.08 {
+		t.Fatalf("Get(EUR/USD) = (%v, %v), want (1.08, true)", val, ok)
+	}
+	if stored(second, "GBP/USD") {
+		t.Fatal("expired GBP/USD was replayed")
+	}
+	if stored(second, "USD/JPY") {
+		t.Fatal("deleted USD/JPY was replayed")
+	}
+	// Absolute expiry times survive the restart.
+	if _, ttl, _ := second.GetWithExpiry("EUR/USD"); ttl != time.Hour-2*time.Second {
+		t.Fatalf("EUR/USD TTL after replay = %v, want %v", ttl, time.Hour-2*time.Second)
+	}
+}
+
+func TestWALTornTail(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, path string, lastRecord int64)
+	}{
+		{"truncated payload", func(t *testing.T, path string, lastRecord int64) {
+			if err := os.Truncate(path, fileSize(t, path)-3); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"truncated frame", func(t *testing.T, path string, lastRecord int64) {
+			if err := os.Truncate(path, lastRecord+5); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"bad checksum", func(t *testing.T, path string, lastRecord int64) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[len(data)-2] ^= 0xff
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"oversized length", func(t *testing.T, path string, lastRecord int64) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			copy(data[lastRecord:], []byte{0xff, 0xff, 0xff, 0xff})
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fx.wal")
+			clock := NewManualClock(time.Unix(1_700_000_000, 0))
+
+			first := openWALCache(t, path, clock)
+			first.Set("USD/THB", 36.5)
+			lastRecord := fileSize(t, path)
+			first.Set("EUR/USD", 1.08)
+			tt.corrupt(t, path, lastRecord)
+
+			second := openWALCache(t, path, clock)
+			if val, ok := second.Get("USD/THB"); !ok || val != 36.5 {
+				t.Fatalf("Get(USD/THB) = (%v, %v), want (36.5, true)", val, ok)
+			}
+			if stored(second, "EUR/USD") {
+				t.Fatal("torn EUR/USD record was replayed")
+			}
+			if got := fileSize(t, path); got != lastRecord {
+				t.Fatalf("log size after recovery = %d, want torn tail cut at %d", got, lastRecord)
+			}
+
+			// New records go after the last intact one and replay cleanly.
+			second.Set("GBP/USD", 1.27)
+			third := openWALCache(t, path, clock)
+			if got := third.Len(); got != 2 {
+				t.Fatalf("Len() after second restart = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestWALPartialHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fx.wal")
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+	// A crash while the header of a new log was being written.
+	if err := os.WriteFile(path, []byte("TTL"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	first := openWALCache(t, path, clock)
+	if got := fileSize(t, path); got != int64(len(walHeader)) {
+		t.Fatalf("log size after recovery = %d, want just the %d-byte header", got, len(walHeader))
+	}
+	first.Set("USD/THB", 36.5)
+
+	for range 2 {
+		cache := openWALCache(t, path, clock)
+		if val, ok := cache.Get("USD/THB"); !ok || val != 36.5 {
+			t.Fatalf("Get(USD/THB) after restart = (%v, %v), want (36.5, true)", val, ok)
+		}
+	}
+}
+
+func TestCompactWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fx.wal")
+	clock := NewManualClock(time.Unix(1_700_000_000, 0))
+
+	first := openWALCache(t, path, clock)
+	for i := 0; i < 10; i++ {
+		first.Set("USD/THB", 36+float64(i)/10)
+	}
+	first.Set("EUR/USD", 1.08)
+	if err := first.CompactWAL(); err != nil {
+		t.Fatalf("CompactWAL: %v", err)
+	}
+	if got := fileSize(t, path); got != int64(len(walHeader)) {
+		t.Fatalf("log size after compaction = %d, want %d", got, len(walHeader))
+	}
+	first.Delete("EUR/USD")
+	first.Set("GBP/USD", 1.27)
+
+	second := openWALCache(t, path, clock)
+	if val, ok := second.Get("USD/THB"); !ok || val != 36.9 {
+		t.Fatalf("Get(USD/THB) = (%v, %v), want (36.9, true)", val, ok)
+	}
+	if stored(second, "EUR/USD") {
+		t.Fatal("EUR/USD deleted after compaction was restored")
+	}
+	if !second.Has("GBP/USD") {
+		t.Fatal("GBP/USD set after compaction was not restored")
+	}
+}
+
+func TestWALErrorHandlerMayUseCache(t *testing.T) {
+	reported := make(chan int, 1)
+	var cache *FXRateCache
+	cache, err := NewTTLCacheWithConfig[string, float64](Config{
+		DefaultTTL:      time.Hour,
+		JanitorInterval: time.Hour,
+		WALPath:         filepath.Join(t.TempDir(), "fx.wal"),
+		// Len takes the cache lock, which must not be held here.
+		OnError: func(error) { reported <- cache.Len() },
+	})
+	if err != nil {
+		t.Fatalf("NewTTLCacheWithConfig: %v", err)
+	}
+	defer cache.StopJanitor()
+	// Make the next append fail.
+	cache.wal.f.Close()
+
+	done := make(chan struct{})
+	go func() {
+		cache.Set("USD/THB", 36.5)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Set deadlocked reporting the failed append")
+	}
+	if n := <-reported; n != 1 {
+		t.Errorf("Len in OnError = %d, want 1", n)
+	}
+}
+
+func TestCompactWALWithoutLog(t *testing.T) {
+	cache, _ := newTestCache(t)
+	if err := cache.CompactWAL(); err == nil {
+		t.Fatal("CompactWAL without a log succeeded")
+	}
+}